@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/crawler"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"github.com/gabrielmoura/WebCrawler/infra/db"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var ErrUnknownJobCommand = errors.New("unknown job command, use: job list | pause <id> | resume <id> | delete <id>")
+
+// runJob gerencia os jobs: list, pause, resume (com a configuração gravada) e delete
+func runJob(args []string) error {
+	if len(args) == 0 {
+		return ErrUnknownJobCommand
+	}
+	if args[0] == "list" {
+		return listJobs()
+	}
+	if len(args) != 2 {
+		return ErrUnknownJobCommand
+	}
+	id := args[1]
+	if err := config.ValidateJob(id); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "pause":
+		if err := db.SetJobStatus(id, data.JobPaused); err != nil {
+			return err
+		}
+		log.Logger.Info("Job paused, the crawler stops after the current batch", zap.String("Job", id))
+		return nil
+	case "resume":
+		job, err := db.ReadJob(id)
+		if err != nil {
+			return err
+		}
+		if err := config.ApplySnapshot(job.Config); err != nil {
+			return err
+		}
+		log.Logger.Info("Resuming job", zap.String("Job", id))
+		return runCrawl()
+	case "delete":
+		return deleteJob(id)
+	}
+	return ErrUnknownJobCommand
+}
+
+// listJobs imprime os jobs com status, número de páginas e hosts desacelerados pelo controle de taxa
+func listJobs() error {
+	jobs, err := db.ListJobs()
+	if err != nil {
+		return fmt.Errorf("error listing jobs: %w", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tSTATUS\tPAGES\tTHROTTLED\tCREATED\tUPDATED")
+	for _, job := range jobs {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", job.ID, job.Status, job.Pages, throttledHosts(job.Throttle),
+			job.CreatedAt.Format(time.DateTime), job.UpdatedAt.Format(time.DateTime))
+	}
+	return w.Flush()
+}
+
+// throttledHosts resume os hosts desacelerados gravados no job: host (atraso), ou "-" sem hosts
+func throttledHosts(stats []byte) string {
+	var hosts []crawler.HostStat
+	if err := json.Unmarshal(stats, &hosts); err != nil || len(hosts) == 0 {
+		return "-"
+	}
+	summary := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		summary = append(summary, fmt.Sprintf("%s (%s)", host.Host, host.Delay))
+	}
+	return strings.Join(summary, ", ")
+}
+
+// deleteJob apaga as páginas e arestas do job e, no cache indicado pela sua configuração, a fila e as visitas
+func deleteJob(id string) error {
+	if job, err := db.ReadJob(id); err == nil {
+		if err := config.ApplySnapshot(job.Config); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, db.ErrJobNotFound) {
+		return err
+	}
+	if config.Conf.Cache.Mode != "mem" {
+		if err := cache.InitCache(); err != nil {
+			return fmt.Errorf("error opening cache: %w", err)
+		}
+		defer cache.CloseCache()
+		if err := cache.DeleteJob(id); err != nil {
+			return err
+		}
+	}
+	if err := db.DeleteJob(id); err != nil {
+		return err
+	}
+	log.Logger.Info("Job deleted", zap.String("Job", id))
+	return nil
+}
@@ -1,18 +1,69 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"github.com/gabrielmoura/WebCrawler/config"
 	"github.com/gabrielmoura/WebCrawler/infra/cache"
 	"github.com/gabrielmoura/WebCrawler/infra/crawler"
 	"github.com/gabrielmoura/WebCrawler/infra/db"
 	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"github.com/gabrielmoura/WebCrawler/infra/scope"
+	"github.com/gabrielmoura/WebCrawler/infra/warc"
+	"go.uber.org/zap"
 )
 
 func main() {
 	log.InitLogger()
-	config.LoadConfig()
+	if err := config.LoadConfig(); err != nil {
+		log.Logger.Fatal("error loading config", zap.Error(err))
+	}
 	db.InitDB()
-	cache.InitCache()
 
-	crawler.HandleQueue(config.Conf.InicialURL)
+	switch flag.Arg(0) {
+	case "pagerank":
+		if err := runPageRank(); err != nil {
+			log.Logger.Fatal("error computing pagerank", zap.Error(err))
+		}
+	case "export-graph":
+		if err := runExportGraph(flag.Args()[1:]); err != nil {
+			log.Logger.Fatal("error exporting graph", zap.Error(err))
+		}
+	case "scope":
+		if err := runScope(flag.Args()[1:]); err != nil {
+			log.Logger.Fatal("error testing scope", zap.Error(err))
+		}
+	case "job":
+		if err := runJob(flag.Args()[1:]); err != nil {
+			log.Logger.Fatal("error managing job", zap.Error(err))
+		}
+	case "reprocess":
+		if err := runReprocess(flag.Args()[1:]); err != nil {
+			log.Logger.Fatal("error reprocessing WARC files", zap.Error(err))
+		}
+	default:
+		if err := runCrawl(); err != nil {
+			log.Logger.Fatal("error running crawler", zap.Error(err))
+		}
+	}
+}
+
+// runCrawl executa o crawler no job atual
+func runCrawl() error {
+	if err := cache.InitCache(); err != nil {
+		return fmt.Errorf("error opening cache: %w", err)
+	}
+	if err := scope.Init(); err != nil {
+		return fmt.Errorf("error loading scope rules: %w", err)
+	}
+	if err := warc.InitWarc(); err != nil {
+		return fmt.Errorf("error initializing WARC writer: %w", err)
+	}
+	defer warc.CloseWarc()
+	seeds, err := crawler.LoadSeeds()
+	if err != nil {
+		return fmt.Errorf("error loading seeds: %w", err)
+	}
+	crawler.HandleQueue(seeds)
+	return nil
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"github.com/gabrielmoura/WebCrawler/infra/db"
+	"github.com/gabrielmoura/WebCrawler/infra/graph"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+)
+
+// runPageRank calcula PageRank e HITS sobre o grafo de links armazenado e grava as pontuações nas páginas.
+// O PageRank é normalizado para que a página mais relevante tenha 1, facilitando o uso no ranking da busca.
+func runPageRank() error {
+	links, err := db.AllLinks()
+	if err != nil {
+		return fmt.Errorf("error reading links: %w", err)
+	}
+	g := graph.NewGraph(links)
+	log.Logger.Info("Computing PageRank", zap.Int("Nodes", len(g.Nodes)), zap.Int("Edges", len(links)))
+
+	rank := g.PageRank(config.Conf.Rank.Damping, config.Conf.Rank.Iterations, config.Conf.Rank.Tolerance)
+	hubs, authorities := g.HITS(config.Conf.Rank.Iterations, config.Conf.Rank.Tolerance)
+
+	var maxRank float64
+	for _, r := range rank {
+		maxRank = max(maxRank, r)
+	}
+
+	scores := make([]data.PageScore, 0, len(g.Nodes))
+	for _, url := range g.Nodes {
+		score := data.PageScore{Url: url, Hub: hubs[url], Authority: authorities[url]}
+		if maxRank > 0 {
+			score.PageRank = rank[url] / maxRank
+		}
+		scores = append(scores, score)
+	}
+
+	if err := db.WriteScores(scores); err != nil {
+		return fmt.Errorf("error writing scores: %w", err)
+	}
+	log.Logger.Info("PageRank written", zap.Int("Pages", len(scores)))
+	return nil
+}
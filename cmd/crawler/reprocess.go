@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"github.com/gabrielmoura/WebCrawler/infra/crawler"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"github.com/gabrielmoura/WebCrawler/infra/warc"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// runReprocess executa novamente a extração sobre arquivos WARC, informados diretamente ou por diretório
+func runReprocess(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: reprocess <file.warc.gz|dir>...")
+	}
+
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && warc.IsWarcFile(entry.Name()) {
+				files = append(files, filepath.Join(arg, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+
+	var total int
+	for _, file := range files {
+		count, err := crawler.ReprocessWarc(file)
+		total += count
+		if err != nil {
+			log.Logger.Error("error reprocessing WARC", zap.String("File", file), zap.Error(err))
+			continue
+		}
+		log.Logger.Info("WARC reprocessed", zap.String("File", file), zap.Int("Pages", count))
+	}
+	log.Logger.Info("Reprocess finished", zap.Int("Files", len(files)), zap.Int("Pages", total))
+	return nil
+}
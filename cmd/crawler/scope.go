@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/crawler"
+	"github.com/gabrielmoura/WebCrawler/infra/scope"
+	"github.com/gabrielmoura/go/pkg/ternary"
+)
+
+var ErrUnknownScopeCommand = errors.New("unknown scope command, use: scope test <url>")
+
+// runScope executa os subcomandos de escopo. "test" explica a decisão das regras e do SCOPE das seeds para cada URL.
+func runScope(args []string) error {
+	if len(args) == 0 || args[0] != "test" {
+		return ErrUnknownScopeCommand
+	}
+	fs := flag.NewFlagSet("scope test", flag.ExitOnError)
+	depth := fs.Int("depth", 1, "Depth at which the URL would be enqueued")
+	seeds := fs.String("seeds", "", "Seed URLs for the same-host/same-domain presets, comma separated (default seeds from config)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return ErrUnknownScopeCommand
+	}
+	if err := cache.InitCache(); err != nil {
+		return fmt.Errorf("error opening cache: %w", err)
+	}
+	if err := scope.Init(); err != nil {
+		return err
+	}
+	// As seeds são carregadas como no crawl; -seeds substitui SEEDS e o arquivo de seeds
+	if *seeds != "" {
+		config.Conf.Seeds, config.Conf.SeedFile = nil, ""
+		for _, seed := range splitComma(*seeds) {
+			config.Conf.Seeds = append(config.Conf.Seeds, config.Seed{URL: seed})
+		}
+	}
+	list, err := crawler.LoadSeeds()
+	if err != nil {
+		return fmt.Errorf("error loading seeds: %w", err)
+	}
+
+	for _, link := range fs.Args() {
+		decision := crawler.ExplainScope(link, *depth, list)
+		fmt.Printf("%s: %s\n", link, ternary.Ternary(decision.Allowed, "included", "excluded"))
+		for _, line := range decision.Trace {
+			fmt.Printf("  %s\n", line)
+		}
+		fmt.Printf("  => %s\n", decision.Reason)
+	}
+	return nil
+}
@@ -1,7 +1,18 @@
 package config
 
+// Índices da fila e das visitas, separados por job (ver SetJob)
 var QueueName = "queueIndex"
+var QueuedIndexName = "queuedIndex"
 var VisitedIndexName = "visitedIndex"
+var FetchStateIndexName = "fetchStateIndex"
+var ScheduleIndexName = "scheduleIndex"
+var RefererIndexName = "refererIndex"
+var SeedIndexName = "seedIndex"
+var BudgetIndexName = "budgetIndex"
+var CookieIndexName = "cookieIndex"
+
+// Índices compartilhados entre os jobs
+var I2PAddressIndexName = "i2pAddressIndex"
 
 // AcceptableMimeTypes Mimes aceitos, checagem quando visitado
 var AcceptableMimeTypes = []string{
@@ -119,3 +130,70 @@ var CommonStopWords = map[string][]string{
 		"向", "生", "里", "果", "再", "两", "并", "而", "些", "定",
 	},
 }
+
+// TextSearchConfigs Mapeia o idioma detectado da página para a configuração de busca textual do Postgres.
+// Idiomas sem configuração própria usam "simple" (sem stemming).
+var TextSearchConfigs = map[string]string{
+	"en": "english",
+	"pt": "portuguese",
+	"ru": "russian",
+	"es": "spanish",
+	"fr": "french",
+	"de": "german",
+	"it": "italian",
+	"nl": "dutch",
+}
+
+// DefaultTextSearchConfig Configuração usada quando o idioma não é conhecido
+var DefaultTextSearchConfig = "simple"
+
+// DefaultBanMarkers Textos que, no início de uma página, indicam captcha ou bloqueio pelo site.
+// São marcadores de desafios específicos: "captcha" sozinho aparece em páginas comuns (formulários, scripts).
+var DefaultBanMarkers = []string{
+	"g-recaptcha",
+	"h-captcha",
+	"hcaptcha.com",
+	"cf-challenge",
+	"challenge-form",
+}
+
+// DefaultLogoutPatterns URLs de saída não buscadas em domínios autenticados (AUTH.LOGOUT_PATTERNS)
+var DefaultLogoutPatterns = []string{
+	`(?i)/(log-?out|sign-?out|log-?off|sair)([/.?#]|$)`,
+	`(?i)[?&](action|do|mode|op)=(log-?out|sign-?out|log-?off)(&|$)`,
+}
+
+// DefaultI2PJumpServices Serviços de jump do I2P, %s é substituído pelo host procurado
+var DefaultI2PJumpServices = []string{
+	"http://stats.i2p/cgi-bin/jump.cgi?a=%s",
+	"http://reg.i2p/jump/%s",
+	"http://i2pjump.i2p/jump/%s",
+	"http://notbob.i2p/cgi-bin/jump.cgi?q=%s",
+}
+
+// DefaultLinkSources Elementos e atributos de onde links são extraídos, mantidos iguais a LINK_SOURCES do example_config.yml
+var DefaultLinkSources = []LinkSource{
+	{Element: "a", Attr: "href", Type: "page"},
+	{Element: "area", Attr: "href", Type: "page"},
+	{Element: "link", Attr: "href", Rel: []string{"alternate", "next", "prev", "canonical"}, Type: "page"},
+	{Element: "iframe", Attr: "src", Type: "page"},
+	{Element: "frame", Attr: "src", Type: "page"},
+	{Element: "form", Attr: "action", Type: "page"},
+	{Element: "meta", Attr: "content", Type: "page"},
+	{Element: "link", Attr: "href", Rel: []string{"stylesheet", "icon", "preload", "manifest"}, Type: "resource"},
+	{Element: "img", Attr: "src", Type: "resource"},
+	{Element: "img", Attr: "srcset", Type: "resource"},
+	{Element: "source", Attr: "src", Type: "resource"},
+	{Element: "source", Attr: "srcset", Type: "resource"},
+	{Element: "script", Attr: "src", Type: "resource"},
+	{Element: "video", Attr: "src", Type: "resource"},
+	{Element: "audio", Attr: "src", Type: "resource"},
+	{Element: "embed", Attr: "src", Type: "resource"},
+	{Element: "object", Attr: "data", Type: "resource"},
+}
+
+// DefaultMaxBodySizes Limite do corpo por prefixo do tipo de conteúdo, sobrepõe HTTP.MAX_BODY_SIZE
+var DefaultMaxBodySizes = map[string]int64{
+	"text/plain":       2 << 20,
+	"application/json": 5 << 20,
+}
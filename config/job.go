@@ -0,0 +1,255 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// DefaultJob job usado sem -job; seus índices mantêm os nomes sem prefixo
+const DefaultJob = "default"
+
+var ErrInvalidJob = errors.New("invalid job ID")
+
+// Job identificador do crawl atual. A fila, as visitas e as páginas gravadas são separadas por job.
+var Job = DefaultJob
+
+var jobRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// jobIndexBases nomes base dos índices separados por job
+var jobIndexBases = []struct {
+	name *string
+	base string
+}{
+	{&QueueName, "queueIndex"},
+	{&QueuedIndexName, "queuedIndex"},
+	{&VisitedIndexName, "visitedIndex"},
+	{&FetchStateIndexName, "fetchStateIndex"},
+	{&ScheduleIndexName, "scheduleIndex"},
+	{&RefererIndexName, "refererIndex"},
+	{&SeedIndexName, "seedIndex"},
+	{&BudgetIndexName, "budgetIndex"},
+	{&CookieIndexName, "cookieIndex"},
+}
+
+// isReservedJob nomes de índices não podem ser jobs: as chaves do job (<job>:<base>) cairiam no índice do job padrão
+func isReservedJob(job string) bool {
+	if job == I2PAddressIndexName {
+		return true
+	}
+	for _, index := range jobIndexBases {
+		if job == index.base {
+			return true
+		}
+	}
+	return false
+}
+
+// jobIndexName nome do índice no job: <job>:<base>, ou apenas a base no job padrão
+func jobIndexName(job, base string) string {
+	if job == DefaultJob {
+		return base
+	}
+	return job + ":" + base
+}
+
+// JobIndexNames nomes dos índices do job, usados para apagar seus dados do cache
+func JobIndexNames(job string) []string {
+	names := make([]string, 0, len(jobIndexBases))
+	for _, index := range jobIndexBases {
+		names = append(names, jobIndexName(job, index.base))
+	}
+	return names
+}
+
+// ValidateJob verifica se o ID do job contém apenas letras, números, _ e - e não é o nome de um índice
+func ValidateJob(job string) error {
+	if !jobRegex.MatchString(job) || isReservedJob(job) {
+		return fmt.Errorf("%w: %q", ErrInvalidJob, job)
+	}
+	return nil
+}
+
+// SetJob define o job atual e os nomes dos seus índices no cache
+func SetJob(job string) error {
+	if err := ValidateJob(job); err != nil {
+		return err
+	}
+	Job = job
+	for _, index := range jobIndexBases {
+		*index.name = jobIndexName(job, index.base)
+	}
+	return nil
+}
+
+// Snapshot serializa a configuração atual, gravada com o job para que ele seja retomado com as mesmas opções.
+// Segredos não são gravados: a URI do banco, as credenciais dos proxies, os cabeçalhos configurados
+// (ex: X-Api-Key) e os campos fixos dos formulários de login vêm da invocação que retoma o job.
+// Seeds lidas da entrada padrão (SEED_FILE "-") são gravadas em SEEDS, para que a retomada não aguarde a entrada.
+func Snapshot(seeds []Seed) ([]byte, error) {
+	cfg := *Conf
+	if cfg.SeedFile == "-" {
+		cfg.Seeds, cfg.SeedFile = seeds, ""
+	}
+	// Os limites usados pelo crawler são os das flags
+	cfg.MaxDepth = *MaxDepth
+	cfg.MaxConcurrency = *MaxConcurrency
+	cfg.PostgresURI = ""
+	if cfg.Proxy != nil {
+		proxy := *cfg.Proxy
+		proxy.ProxyURL = stripCredentials(proxy.ProxyURL)
+		proxy.ProxyURLs = mapProxyURLs(proxy.ProxyURLs, stripCredentials)
+		proxy.Routes = make([]ProxyRoute, len(cfg.Proxy.Routes))
+		for i, route := range cfg.Proxy.Routes {
+			route.ProxyURL = stripCredentials(route.ProxyURL)
+			route.ProxyURLs = mapProxyURLs(route.ProxyURLs, stripCredentials)
+			proxy.Routes[i] = route
+		}
+		cfg.Proxy = &proxy
+	}
+	if cfg.Headers != nil {
+		headers := *cfg.Headers
+		headers.Global = nil
+		headers.Domains = make([]DomainHeaders, len(cfg.Headers.Domains))
+		for i, domain := range cfg.Headers.Domains {
+			domain.Headers = nil
+			headers.Domains[i] = domain
+		}
+		cfg.Headers = &headers
+	}
+	cfg.Auth = make([]Auth, len(Conf.Auth))
+	for i, auth := range Conf.Auth {
+		if auth.Form != nil {
+			form := *auth.Form
+			form.Fields = nil
+			auth.Form = &form
+		}
+		cfg.Auth[i] = auth
+	}
+	return json.Marshal(cfg)
+}
+
+// restoreSecrets devolve ao snapshot os cabeçalhos e os campos de login da configuração atual,
+// pelo domínio de cada entrada
+func restoreSecrets(saved, current *Config) {
+	if saved.Headers != nil && current.Headers != nil {
+		saved.Headers.Global = current.Headers.Global
+		for i, domain := range saved.Headers.Domains {
+			for _, cur := range current.Headers.Domains {
+				if cur.Domain == domain.Domain {
+					saved.Headers.Domains[i].Headers = cur.Headers
+				}
+			}
+		}
+	}
+	for i, auth := range saved.Auth {
+		if auth.Form == nil {
+			continue
+		}
+		for _, cur := range current.Auth {
+			if cur.Domain == auth.Domain && cur.Form != nil {
+				saved.Auth[i].Form.Fields = cur.Form.Fields
+			}
+		}
+	}
+}
+
+// stripCredentials remove usuário e senha da URL do proxy
+func stripCredentials(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	u.User = nil
+	return u.String()
+}
+
+func mapProxyURLs(urls []string, fn func(string) string) []string {
+	if urls == nil {
+		return nil
+	}
+	mapped := make([]string, len(urls))
+	for i, raw := range urls {
+		mapped[i] = fn(raw)
+	}
+	return mapped
+}
+
+// restoreCredentials devolve às URLs de proxy do snapshot as credenciais do mesmo proxy (esquema e host)
+// na configuração atual
+func restoreCredentials(saved, current *Proxy) {
+	if saved == nil || current == nil {
+		return
+	}
+	users := make(map[string]*url.Userinfo)
+	collect := func(raw string) {
+		if u, err := url.Parse(raw); err == nil && u.User != nil {
+			users[u.Scheme+"://"+u.Host] = u.User
+		}
+	}
+	for _, raw := range append([]string{current.ProxyURL}, current.ProxyURLs...) {
+		collect(raw)
+	}
+	for _, route := range current.Routes {
+		for _, raw := range append([]string{route.ProxyURL}, route.ProxyURLs...) {
+			collect(raw)
+		}
+	}
+	if len(users) == 0 {
+		return
+	}
+	restore := func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil || u.User != nil || users[u.Scheme+"://"+u.Host] == nil {
+			return raw
+		}
+		u.User = users[u.Scheme+"://"+u.Host]
+		return u.String()
+	}
+	saved.ProxyURL = restore(saved.ProxyURL)
+	saved.ProxyURLs = mapProxyURLs(saved.ProxyURLs, restore)
+	for i := range saved.Routes {
+		saved.Routes[i].ProxyURL = restore(saved.Routes[i].ProxyURL)
+		saved.Routes[i].ProxyURLs = mapProxyURLs(saved.Routes[i].ProxyURLs, restore)
+	}
+}
+
+// ApplySnapshot restaura a configuração gravada de um job, mantendo a URI do banco, as credenciais
+// dos proxies, os cabeçalhos e os campos de login da invocação atual
+func ApplySnapshot(snapshot []byte) error {
+	var cfg Config
+	if err := json.Unmarshal(snapshot, &cfg); err != nil {
+		return fmt.Errorf("error reading job config: %w", err)
+	}
+	if Conf != nil {
+		cfg.PostgresURI = Conf.PostgresURI
+		restoreCredentials(cfg.Proxy, Conf.Proxy)
+		restoreSecrets(&cfg, Conf)
+	}
+	if err := validateAuth(cfg.Auth); err != nil {
+		return err
+	}
+	if cfg.Budget == nil {
+		cfg.Budget = &Budget{}
+	}
+	// Limites do orçamento passados por flag substituem os gravados, para retomar um job esgotado
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "maxPages":
+			cfg.Budget.MaxPages = *maxPages
+		case "maxBytes":
+			cfg.Budget.MaxBytes = *maxBytes
+		case "maxDuration":
+			cfg.Budget.MaxDuration = *maxDuration
+		case "maxPagesPerHost":
+			cfg.Budget.MaxPagesPerHost = *maxPagesPerHost
+		}
+	})
+	Conf = &cfg
+	*MaxDepth = cfg.MaxDepth
+	*MaxConcurrency = cfg.MaxConcurrency
+	return SetJob(cfg.Job)
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"strconv"
+)
+
+// SetBudgetCounter grava um contador do orçamento do crawl (páginas, bytes ou páginas de um host)
+func SetBudgetCounter(name string, value int64) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	key := []byte(fmt.Sprintf("%s:%s", config.BudgetIndexName, name))
+	err := cdb.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, []byte(strconv.FormatInt(value, 10)))
+	})
+	if err != nil {
+		return fmt.Errorf("error setting budget counter: %v", err)
+	}
+	return nil
+}
+
+// GetBudgetCounter retorna um contador do orçamento do crawl, 0 se não existir
+func GetBudgetCounter(name string) int64 {
+	var value int64
+	key := []byte(fmt.Sprintf("%s:%s", config.BudgetIndexName, name))
+	_ = cdb.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			value, _ = strconv.ParseInt(string(val), 10, 64)
+			return nil
+		})
+	})
+	return value
+}
+
+// heldKey chave de uma página guardada fora da fila por ter atingido a cota do host: budgetIndex:held:<url>
+func heldKey(url string) []byte {
+	return []byte(fmt.Sprintf("%s:held:%s", config.BudgetIndexName, url))
+}
+
+// HoldURL guarda fora da fila uma página de host que atingiu a cota de páginas
+func HoldURL(url string, depth int) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	err := cdb.Update(func(txn *badger.Txn) error {
+		return txn.Set(heldKey(url), []byte(strconv.Itoa(depth)))
+	})
+	if err != nil {
+		return fmt.Errorf("error holding url: %v", err)
+	}
+	return nil
+}
+
+// PopHeldURLs remove e retorna as páginas guardadas pela cota dos hosts
+func PopHeldURLs() ([]QueueType, error) {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+
+	var held []QueueType
+	err := cdb.Update(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := heldKey("")
+		var keys [][]byte
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			var depth int
+			err := item.Value(func(val []byte) error {
+				depth, _ = strconv.Atoi(string(val))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			held = append(held, QueueType{Url: string(item.Key()[len(prefix):]), Depth: depth})
+			keys = append(keys, item.KeyCopy(nil))
+		}
+		for _, key := range keys {
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading held urls: %v", err)
+	}
+	return held, nil
+}
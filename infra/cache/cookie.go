@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"net/http"
+)
+
+// StoredCookie cookie persistido com a URL em que foi recebido, necessária para restaurá-lo no jar
+type StoredCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+func cookieKey(jar, key string) []byte {
+	return []byte(fmt.Sprintf("%s:%s:%s", config.CookieIndexName, jar, key))
+}
+
+// SetCookie grava um cookie do jar informado
+func SetCookie(jar, key string, cookie StoredCookie) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	value, err := json.Marshal(cookie)
+	if err != nil {
+		return fmt.Errorf("error encoding cookie: %v", err)
+	}
+	err = cdb.Update(func(txn *badger.Txn) error {
+		return txn.Set(cookieKey(jar, key), value)
+	})
+	if err != nil {
+		return fmt.Errorf("error setting cookie: %v", err)
+	}
+	return nil
+}
+
+// DeleteCookie remove um cookie do jar informado
+func DeleteCookie(jar, key string) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	err := cdb.Update(func(txn *badger.Txn) error {
+		return txn.Delete(cookieKey(jar, key))
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting cookie: %v", err)
+	}
+	return nil
+}
+
+// LoadCookies recupera todos os cookies persistidos do jar informado
+func LoadCookies(jar string) ([]StoredCookie, error) {
+	var cookies []StoredCookie
+	err := cdb.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := cookieKey(jar, "")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var cookie StoredCookie
+				if err := json.Unmarshal(val, &cookie); err != nil {
+					return err
+				}
+				cookies = append(cookies, cookie)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading cookies: %v", err)
+	}
+	return cookies, nil
+}
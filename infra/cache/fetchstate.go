@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+)
+
+// SetFetchState grava os validadores (ETag, Last-Modified, hash) da última busca de uma URL
+func SetFetchState(url string, state data.FetchState) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	value, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error encoding fetch state: %v", err)
+	}
+	key := []byte(fmt.Sprintf("%s:%s", config.FetchStateIndexName, url))
+	err = cdb.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("error setting fetch state: %v", err)
+	}
+	return nil
+}
+
+// GetFetchState retorna os validadores da última busca de uma URL
+func GetFetchState(url string) (data.FetchState, bool) {
+	var state data.FetchState
+	key := []byte(fmt.Sprintf("%s:%s", config.FetchStateIndexName, url))
+	err := cdb.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &state)
+		})
+	})
+	if err != nil {
+		return data.FetchState{}, false
+	}
+	return state, true
+}
+
+// DeleteFetchState apaga o estado da última busca de uma URL
+func DeleteFetchState(url string) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	key := []byte(fmt.Sprintf("%s:%s", config.FetchStateIndexName, url))
+	err := cdb.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting fetch state: %v", err)
+	}
+	return nil
+}
+
+// fetchAliasKey chave do apelido de uma URL redirecionada: fetchStateIndex:alias:<url>
+func fetchAliasKey(url string) []byte {
+	return []byte(fmt.Sprintf("%s:alias:%s", config.FetchStateIndexName, url))
+}
+
+// SetFetchAlias liga uma URL redirecionada à URL final, sob a qual está o estado da busca
+func SetFetchAlias(url, finalUrl string) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	err := cdb.Update(func(txn *badger.Txn) error {
+		return txn.Set(fetchAliasKey(url), []byte(finalUrl))
+	})
+	if err != nil {
+		return fmt.Errorf("error setting fetch alias: %v", err)
+	}
+	return nil
+}
+
+// GetFetchAlias retorna a URL final de uma URL redirecionada
+func GetFetchAlias(url string) (string, bool) {
+	var finalUrl string
+	err := cdb.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(fetchAliasKey(url))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			finalUrl = string(val)
+			return nil
+		})
+	})
+	return finalUrl, err == nil && finalUrl != ""
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gabrielmoura/WebCrawler/config"
+)
+
+// SetI2PAddress grava o endereço b32 aprendido para um host .i2p
+func SetI2PAddress(host, b32 string) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	key := []byte(fmt.Sprintf("%s:%s", config.I2PAddressIndexName, host))
+	err := cdb.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, []byte(b32))
+	})
+	if err != nil {
+		return fmt.Errorf("error setting i2p address: %v", err)
+	}
+	return nil
+}
+
+// GetI2PAddress retorna o endereço b32 aprendido para um host .i2p
+func GetI2PAddress(host string) (string, bool) {
+	var b32 string
+	key := []byte(fmt.Sprintf("%s:%s", config.I2PAddressIndexName, host))
+	err := cdb.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			b32 = string(val)
+			return nil
+		})
+	})
+	if err != nil {
+		return "", false
+	}
+	return b32, true
+}
@@ -41,9 +41,17 @@ func InitCache() error {
 
 	que := NewBadgerQueue(cdb)
 	queue = que
-	go OptimizeCache()
+	// O cache em memória não tem value log a compactar
+	if config.Conf.Cache.Mode != "mem" {
+		go OptimizeCache()
+	}
 	return nil
 }
+
+// CloseCache fecha o banco do cache, gravando as alterações pendentes
+func CloseCache() error {
+	return cdb.Close()
+}
 func SyncCache() error {
 	time.Sleep(1 * time.Second)
 	log.Logger.Info("Syncing cache")
@@ -63,10 +71,9 @@ func SyncCache() error {
 	}
 	return nil
 }
+
+// OptimizeCache compacta periodicamente o value log do cache em disco
 func OptimizeCache() {
-	if config.Conf.Cache.Mode == "mem" {
-		return
-	}
 	for {
 		time.Sleep(2 * time.Minute)
 		log.Logger.Info("Optimizing cache")
@@ -92,6 +99,7 @@ func IsVisited(url string) bool {
 	}
 	return true
 }
+
 func SetVisited(url string) error {
 	blockWrite.RLock()
 	defer blockWrite.RUnlock()
@@ -109,30 +117,44 @@ func SetVisited(url string) error {
 	return nil
 }
 
-func AddToQueue(url string, depth int) error {
-	err := queue.Enqueue(url, depth)
+func AddToQueue(url string, depth, priority int) error {
+	err := queue.Enqueue(url, depth, priority)
 	if err != nil {
 		return fmt.Errorf("error adding to queue: %v", err)
 	}
 	return nil
 }
 func GetFromQueue() (string, int, error) {
-	url, depth, err := queue.Dequeue()
+	item, err := queue.Dequeue()
 	if err != nil {
 		return "", 0, fmt.Errorf("error getting from queue: %v", err)
 	}
-	return url, depth, nil
+	return item.Url, item.Depth, nil
 }
 func GetFromQueueV2(getNumber int) ([]QueueType, error) {
 	var urls []QueueType
 	for i := 0; i < getNumber; i++ {
-		url, depth, err := queue.Dequeue()
+		item, err := queue.Dequeue()
 		if err != nil {
 			return nil, fmt.Errorf("error getting from queue: %v", err)
 		}
-		if url != "" {
-			urls = append(urls, QueueType{Url: url, Depth: depth})
+		if item.Url != "" {
+			urls = append(urls, item)
 		}
 	}
 	return urls, nil
 }
+
+// DeleteJob apaga do cache a fila, as visitas e os demais índices do job
+func DeleteJob(job string) error {
+	blockWrite.Lock()
+	defer blockWrite.Unlock()
+	var prefixes [][]byte
+	for _, name := range config.JobIndexNames(job) {
+		prefixes = append(prefixes, []byte(name+":"))
+	}
+	if err := cdb.DropPrefix(prefixes...); err != nil {
+		return fmt.Errorf("error deleting job from cache: %v", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
 	"github.com/dgraph-io/badger/v4"
 	"github.com/gabrielmoura/WebCrawler/config"
@@ -9,17 +10,43 @@ import (
 
 var queue *BadgerQueue
 
+// MaxPriority maior prioridade da fila; prioridades maiores são retiradas antes
+const MaxPriority = 999
+
 // Queue represents an interface for interacting with a queue data structure.
 type Queue interface {
-	Enqueue(url string, depth int) error
-	Dequeue() (string, int, error)
+	Enqueue(url string, depth, priority int) error
+	Dequeue() (QueueType, error)
 
 	Read() ([]QueueType, error)
-	Delete(url string) error
+	Delete(url string, priority int) error
 }
 type QueueType struct {
-	Url   string `json:"url"`
-	Depth int    `json:"depth"`
+	Url      string `json:"url"`
+	Depth    int    `json:"depth"`
+	Priority int    `json:"priority"`
+}
+
+// queueKey chave ordenada pela prioridade: queueIndex:<999-prioridade>:<url>
+func queueKey(url string, priority int) []byte {
+	priority = max(0, min(priority, MaxPriority))
+	return []byte(fmt.Sprintf("%s:%03d:%s", config.QueueName, MaxPriority-priority, url))
+}
+
+// queuedKey marca a URL na fila com a sua prioridade, para que ela não entre duas vezes com prioridades diferentes
+func queuedKey(url string) []byte {
+	return []byte(fmt.Sprintf("%s:%s", config.QueuedIndexName, url))
+}
+
+// parseQueueKey extrai URL e prioridade da chave; chaves sem prioridade (formato anterior) têm prioridade 0
+func parseQueueKey(key string) (string, int) {
+	rest := key[len(config.QueueName)+1:]
+	if len(rest) > 4 && rest[3] == ':' {
+		if inverted, err := strconv.Atoi(rest[:3]); err == nil {
+			return rest[4:], MaxPriority - inverted
+		}
+	}
+	return rest, 0
 }
 
 // BadgerQueue is an implementation of the Queue interface using BadgerDB.
@@ -32,22 +59,42 @@ func NewBadgerQueue(db *badger.DB) *BadgerQueue {
 	return &BadgerQueue{db: db}
 }
 
-// Enqueue adds a URL to the queue.
-func (q *BadgerQueue) Enqueue(url string, depth int) error {
+// Enqueue adds a URL to the queue. Uma URL já na fila só é movida para uma prioridade maior.
+func (q *BadgerQueue) Enqueue(url string, depth, priority int) error {
 	blockWrite.RLock()
 	defer blockWrite.RUnlock()
-	key := []byte(fmt.Sprintf("%s:%s", config.QueueName, url))
+	priority = max(0, min(priority, MaxPriority))
 	return q.db.Update(func(txn *badger.Txn) error {
-		return txn.Set(key, []byte(strconv.Itoa(depth)))
+		item, err := txn.Get(queuedKey(url))
+		switch {
+		case err == nil:
+			queued := 0
+			_ = item.Value(func(val []byte) error {
+				queued, _ = strconv.Atoi(string(val))
+				return nil
+			})
+			if queued >= priority {
+				return nil
+			}
+			if err := txn.Delete(queueKey(url, queued)); err != nil {
+				return err
+			}
+		case !errors.Is(err, badger.ErrKeyNotFound):
+			return err
+		}
+		if err := txn.Set(queuedKey(url), []byte(strconv.Itoa(priority))); err != nil {
+			return err
+		}
+		return txn.Set(queueKey(url, priority), []byte(strconv.Itoa(depth)))
 	})
 }
 
-// Dequeue retrieves and removes a URL from the queue.
-func (q *BadgerQueue) Dequeue() (string, int, error) {
+// Dequeue retrieves and removes the URL with the highest priority from the queue.
+func (q *BadgerQueue) Dequeue() (QueueType, error) {
 	blockWrite.RLock()
 	defer blockWrite.RUnlock()
 
-	var url string
+	var key string
 	var depth int
 
 	err := q.db.Update(func(txn *badger.Txn) error {
@@ -59,25 +106,29 @@ func (q *BadgerQueue) Dequeue() (string, int, error) {
 		prefix := []byte(config.QueueName)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
-			url = string(item.KeyCopy(nil)) // Copy the key to avoid issues
+			key = string(item.KeyCopy(nil)) // Copy the key to avoid issues
 			item.Value(func(val []byte) error {
 				depth, _ = strconv.Atoi(string(val))
 				return nil
 			})
+			link, _ := parseQueueKey(key)
+			if err := txn.Delete(queuedKey(link)); err != nil {
+				return err
+			}
 			return txn.Delete(item.Key()) // Remove from queue after retrieval
 		}
 		return nil // No items in queue
 	})
 
 	if err != nil {
-		return "", 0, fmt.Errorf("error dequeuing from queue: %v", err)
+		return QueueType{}, fmt.Errorf("error dequeuing from queue: %v", err)
 	}
 
-	if url != "" {
-		url = url[len(config.QueueName)+1:]
+	if key == "" {
+		return QueueType{}, nil
 	}
-
-	return url, depth, nil
+	url, priority := parseQueueKey(key)
+	return QueueType{Url: url, Depth: depth, Priority: priority}, nil
 }
 
 // Read retrieves all URLs from the queue.
@@ -99,7 +150,8 @@ func (q *BadgerQueue) Read() ([]QueueType, error) {
 				depth, _ = strconv.Atoi(string(val))
 				return nil
 			})
-			urls = append(urls, QueueType{Url: url[len(config.QueueName)+1:], Depth: depth})
+			link, priority := parseQueueKey(url)
+			urls = append(urls, QueueType{Url: link, Depth: depth, Priority: priority})
 		}
 		return nil
 	})
@@ -112,11 +164,14 @@ func (q *BadgerQueue) Read() ([]QueueType, error) {
 }
 
 // Delete removes a URL from the queue.
-func (q *BadgerQueue) Delete(url string) error {
+func (q *BadgerQueue) Delete(url string, priority int) error {
 	blockWrite.RLock()
 	defer blockWrite.RUnlock()
-	key := []byte(fmt.Sprintf("%s:%s", config.QueueName, url))
+	key := queueKey(url, priority)
 	return q.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Delete(queuedKey(url)); err != nil {
+			return err
+		}
 		return txn.Delete(key)
 	})
 }
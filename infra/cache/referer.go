@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gabrielmoura/WebCrawler/config"
+)
+
+// SetReferer grava a página onde a URL foi encontrada, mantendo o primeiro referenciador
+func SetReferer(url, referer string) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	key := []byte(fmt.Sprintf("%s:%s", config.RefererIndexName, url))
+	err := cdb.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(key); !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		return txn.Set(key, []byte(referer))
+	})
+	if err != nil {
+		return fmt.Errorf("error setting referer: %v", err)
+	}
+	return nil
+}
+
+// GetReferer retorna a página onde a URL foi encontrada
+func GetReferer(url string) (string, bool) {
+	var referer string
+	key := []byte(fmt.Sprintf("%s:%s", config.RefererIndexName, url))
+	err := cdb.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			referer = string(val)
+			return nil
+		})
+	})
+	if err != nil {
+		return "", false
+	}
+	return referer, true
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleKey chave ordenada pelo horário da visita: scheduleIndex:<unix>:<url>
+func scheduleKey(url string, at time.Time) []byte {
+	return []byte(fmt.Sprintf("%s:%020d:%s", config.ScheduleIndexName, at.Unix(), url))
+}
+
+// ScheduleVisit agenda a próxima visita de uma URL, removendo o agendamento anterior (prev)
+func ScheduleVisit(url string, depth int, prev, next time.Time) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	err := cdb.Update(func(txn *badger.Txn) error {
+		if !prev.IsZero() {
+			if err := txn.Delete(scheduleKey(url, prev)); err != nil {
+				return err
+			}
+		}
+		return txn.Set(scheduleKey(url, next), []byte(strconv.Itoa(depth)))
+	})
+	if err != nil {
+		return fmt.Errorf("error scheduling visit: %v", err)
+	}
+	return nil
+}
+
+// UnscheduleVisit remove o agendamento de uma URL no horário at
+func UnscheduleVisit(url string, at time.Time) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	err := cdb.Update(func(txn *badger.Txn) error {
+		return txn.Delete(scheduleKey(url, at))
+	})
+	if err != nil {
+		return fmt.Errorf("error unscheduling visit: %v", err)
+	}
+	return nil
+}
+
+// NextScheduledVisit retorna o horário da próxima visita agendada; false se não houver agendamentos
+func NextScheduledVisit() (time.Time, bool) {
+	var next time.Time
+	found := false
+	_ = cdb.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(config.ScheduleIndexName + ":")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			parts := strings.SplitN(strings.TrimPrefix(string(it.Item().Key()), string(prefix)), ":", 2)
+			if at, err := strconv.ParseInt(parts[0], 10, 64); err == nil {
+				next, found = time.Unix(at, 0), true
+				return nil
+			}
+		}
+		return nil
+	})
+	return next, found
+}
+
+// PopDueVisits remove do agendamento e retorna até limit URLs com visita vencida em now
+func PopDueVisits(now time.Time, limit int) ([]QueueType, error) {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+
+	var due []QueueType
+	err := cdb.Update(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(config.ScheduleIndexName + ":")
+		var keys [][]byte
+		for it.Seek(prefix); it.ValidForPrefix(prefix) && len(due) < limit; it.Next() {
+			item := it.Item()
+			// Remove o prefixo, que em jobs nomeados também contém ":"
+			parts := strings.SplitN(strings.TrimPrefix(string(item.Key()), string(prefix)), ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			at, err := strconv.ParseInt(parts[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			if at > now.Unix() {
+				break
+			}
+			var depth int
+			err = item.Value(func(val []byte) error {
+				depth, _ = strconv.Atoi(string(val))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			due = append(due, QueueType{Url: parts[1], Depth: depth})
+			keys = append(keys, item.KeyCopy(nil))
+		}
+		for _, key := range keys {
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading due visits: %v", err)
+	}
+	return due, nil
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gabrielmoura/WebCrawler/config"
+)
+
+// SetSeed grava a tag da URL inicial que levou à URL, mantendo a primeira
+func SetSeed(url, tag string) error {
+	blockWrite.RLock()
+	defer blockWrite.RUnlock()
+	key := []byte(fmt.Sprintf("%s:%s", config.SeedIndexName, url))
+	err := cdb.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(key); !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		return txn.Set(key, []byte(tag))
+	})
+	if err != nil {
+		return fmt.Errorf("error setting seed: %v", err)
+	}
+	return nil
+}
+
+// GetSeed retorna a tag da URL inicial que levou à URL
+func GetSeed(url string) (string, bool) {
+	var tag string
+	key := []byte(fmt.Sprintf("%s:%s", config.SeedIndexName, url))
+	err := cdb.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			tag = string(val)
+			return nil
+		})
+	})
+	if err != nil {
+		return "", false
+	}
+	return tag, true
+}
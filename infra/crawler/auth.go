@@ -0,0 +1,273 @@
+package crawler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLoginFailed    = errors.New("login failed")
+	ErrSessionExpired = errors.New("session expired")
+)
+
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthForm   = "form"
+)
+
+const (
+	// loginRetryInterval intervalo mínimo entre tentativas de login falhas no mesmo domínio
+	loginRetryInterval = time.Minute
+	// reloginGrace sessões mais novas que isso não são refeitas (várias páginas detectam a mesma expiração)
+	reloginGrace = 10 * time.Second
+)
+
+// authFor retorna a configuração de autenticação do domínio mais específico que contém o host
+func authFor(link *url.URL) *config.Auth {
+	host := strings.ToLower(link.Hostname())
+	var chosen *config.Auth
+	matched := -1
+	for i := range config.Conf.Auth {
+		auth := &config.Conf.Auth[i]
+		domain := strings.TrimPrefix(strings.ToLower(auth.Domain), ".")
+		if len(domain) > matched && hostMatches(host, domain) {
+			chosen, matched = auth, len(domain)
+		}
+	}
+	return chosen
+}
+
+// hasFormAuth verifica se algum domínio usa login por formulário, que depende do cookie jar
+func hasFormAuth() bool {
+	for _, auth := range config.Conf.Auth {
+		if strings.EqualFold(auth.Type, AuthForm) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAuth adiciona à requisição o cabeçalho Authorization (Basic ou Bearer) configurado para o domínio
+func applyAuth(req *http.Request) {
+	auth := authFor(originURL(req))
+	if auth == nil {
+		return
+	}
+	switch strings.ToLower(auth.Type) {
+	case AuthBasic:
+		req.SetBasicAuth(os.Getenv(auth.UsernameEnv), os.Getenv(auth.PasswordEnv))
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+os.Getenv(auth.TokenEnv))
+	}
+}
+
+// logoutRegexps expressões de LOGOUT_PATTERNS já compiladas
+var logoutRegexps sync.Map
+
+// isLogoutLink verifica se a URL encerraria a sessão de um domínio autenticado:
+// corresponde a LOGOUT_PATTERNS (ou DefaultLogoutPatterns) ou é o destino do formulário de login
+func isLogoutLink(link *url.URL) bool {
+	auth := authFor(link)
+	if auth == nil {
+		return false
+	}
+	if auth.Form != nil && auth.Form.URL != "" && link.String() == auth.Form.URL {
+		return true
+	}
+	patterns := auth.LogoutPatterns
+	if len(patterns) == 0 {
+		patterns = config.DefaultLogoutPatterns
+	}
+	target := link.RequestURI()
+	for _, pattern := range patterns {
+		value, ok := logoutRegexps.Load(pattern)
+		if !ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				continue
+			}
+			value, _ = logoutRegexps.LoadOrStore(pattern, re)
+		}
+		if value.(*regexp.Regexp).MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// loginSession estado do login por formulário de um domínio
+type loginSession struct {
+	mu          sync.Mutex
+	loggedIn    bool
+	lastAttempt time.Time
+	lastErr     error
+}
+
+var loginSessions sync.Map
+
+// ensureLogin executa o login por formulário do domínio da URL, se configurado e ainda não feito
+func ensureLogin(pageUrl string) error {
+	link, err := url.Parse(pageUrl)
+	if err != nil {
+		return err
+	}
+	auth := authFor(link)
+	if auth == nil || !strings.EqualFold(auth.Type, AuthForm) || auth.Form == nil {
+		return nil
+	}
+	value, _ := loginSessions.LoadOrStore(strings.ToLower(auth.Domain), &loginSession{})
+	session := value.(*loginSession)
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.loggedIn {
+		return nil
+	}
+	// Evita repetir logins falhos a cada página do domínio
+	if session.lastErr != nil && time.Since(session.lastAttempt) < loginRetryInterval {
+		return session.lastErr
+	}
+	session.lastAttempt = time.Now()
+	session.lastErr = formLogin(auth)
+	if session.lastErr != nil {
+		log.Logger.Error("Login failed", zap.String("Domain", auth.Domain), zap.Error(session.lastErr))
+		return session.lastErr
+	}
+	log.Logger.Info("Logged in", zap.String("Domain", auth.Domain))
+	session.loggedIn = true
+	return nil
+}
+
+// relogin descarta a sessão do domínio e faz login novamente. Sessões renovadas há pouco
+// por outra página do mesmo domínio são mantidas.
+func relogin(pageUrl string) error {
+	link, err := url.Parse(pageUrl)
+	if err != nil {
+		return err
+	}
+	auth := authFor(link)
+	if auth == nil {
+		return nil
+	}
+	if value, ok := loginSessions.Load(strings.ToLower(auth.Domain)); ok {
+		session := value.(*loginSession)
+		session.mu.Lock()
+		if time.Since(session.lastAttempt) >= reloginGrace {
+			session.loggedIn, session.lastErr = false, nil
+		}
+		session.mu.Unlock()
+	}
+	return ensureLogin(pageUrl)
+}
+
+// formLogin envia o formulário de login com as credenciais das variáveis de ambiente.
+// Com PageURL, os campos ocultos do formulário (ex: token CSRF) são copiados da página de login.
+func formLogin(auth *config.Auth) error {
+	form := auth.Form
+	values := url.Values{}
+	if form.PageURL != "" {
+		resp, err := httpRequest(form.PageURL, nil)
+		if err != nil {
+			return fmt.Errorf("error fetching login page: %w", err)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("error reading login page: %w", err)
+		}
+		for name, value := range hiddenInputs(body) {
+			values.Set(name, value)
+		}
+	}
+	for name, value := range form.Fields {
+		values.Set(name, value)
+	}
+	values.Set(form.UsernameField, os.Getenv(auth.UsernameEnv))
+	values.Set(form.PasswordField, os.Getenv(auth.PasswordEnv))
+
+	req, err := http.NewRequest(http.MethodPost, form.URL, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := doRequest(req)
+	if err != nil {
+		return fmt.Errorf("error sending login form: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("error reading login response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%w: %s", ErrLoginFailed, resp.Status)
+	}
+	if form.SuccessText != "" && !bytes.Contains(body, []byte(form.SuccessText)) {
+		return fmt.Errorf("%w: success text not found", ErrLoginFailed)
+	}
+	return nil
+}
+
+// hiddenInputs campos <input type="hidden"> da página
+func hiddenInputs(body []byte) map[string]string {
+	inputs := make(map[string]string)
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return inputs
+	}
+	var extract func(*html.Node)
+	extract = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "input" {
+			if kind, _ := getAttr(n, "type"); strings.EqualFold(kind, "hidden") {
+				if name, ok := getAttr(n, "name"); ok && name != "" {
+					inputs[name], _ = getAttr(n, "value")
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			extract(c)
+		}
+	}
+	extract(doc)
+	return inputs
+}
+
+// isLoggedOut detecta, em domínios com login por formulário, que a sessão expirou:
+// a resposta é a página de login ou contém um dos LoginMarkers
+func isLoggedOut(pageUrl string, resp *http.Response, body []byte) bool {
+	link, err := url.Parse(pageUrl)
+	if err != nil {
+		return false
+	}
+	auth := authFor(link)
+	if auth == nil || !strings.EqualFold(auth.Type, AuthForm) || auth.Form == nil {
+		return false
+	}
+	form := auth.Form
+	if pageUrl == form.PageURL || pageUrl == form.URL {
+		return false
+	}
+	if form.PageURL != "" && requestURL(resp.Request) == form.PageURL {
+		return true
+	}
+	for _, marker := range form.LoginMarkers {
+		if marker != "" && bytes.Contains(body, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package crawler
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gabrielmoura/WebCrawler/config"
+)
+
+func TestApplyAuthUsesHostHeader(t *testing.T) {
+	setTestConfig(t)
+	t.Setenv("TEST_I2P_USER", "alice")
+	t.Setenv("TEST_I2P_PASSWORD", "secret")
+	config.Conf.Auth = []config.Auth{{Domain: "forum.i2p", Type: AuthBasic, UsernameEnv: "TEST_I2P_USER", PasswordEnv: "TEST_I2P_PASSWORD"}}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://ukeu3k5oycgaauneqgtnvselmt4yemvoilkln7jpvamvfx7dnkdq.b32.i2p/", nil)
+	req.Host = "forum.i2p"
+	applyAuth(req)
+	if user, password, ok := req.BasicAuth(); !ok || user != "alice" || password != "secret" {
+		t.Errorf("basic auth %q/%q (%v), want alice/secret from the forum.i2p entry", user, password, ok)
+	}
+}
+
+func TestIsLogoutLink(t *testing.T) {
+	setTestConfig(t)
+	config.Conf.Auth = []config.Auth{
+		{Domain: "forum.i2p", Type: AuthForm, Form: &config.FormLogin{URL: "http://forum.i2p/login.php"}},
+		{Domain: "wiki.example.com", Type: AuthBasic, LogoutPatterns: []string{`^/Special:UserLogout`}},
+	}
+	tests := []struct {
+		link   string
+		logout bool
+	}{
+		{"http://forum.i2p/logout", true},
+		{"http://forum.i2p/user/sign-out?next=/", true},
+		{"http://forum.i2p/index.php?action=logout&sid=1", true},
+		{"http://forum.i2p/login.php", true},
+		{"http://forum.i2p/topic/logout-bug-report", false},
+		{"http://forum.i2p/index.php?action=view", false},
+		{"http://wiki.example.com/Special:UserLogout", true},
+		{"http://wiki.example.com/logout", false},
+		{"http://other.example.com/logout", false},
+	}
+	for _, tt := range tests {
+		link, _ := url.Parse(tt.link)
+		if got := isLogoutLink(link); got != tt.logout {
+			t.Errorf("isLogoutLink(%s) = %v, want %v", tt.link, got, tt.logout)
+		}
+	}
+}
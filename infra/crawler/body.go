@@ -0,0 +1,147 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/warc"
+	"github.com/gabrielmoura/go/pkg/ternary"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var ErrBodyTooLarge = errors.New("body too large")
+
+// unboundedBodyReserve reserva no orçamento de memória de um corpo sem limite e de tamanho desconhecido.
+// Reservar o orçamento inteiro serializaria as buscas; o corpo pode excedê-la.
+const unboundedBodyReserve int64 = 10 << 20
+
+// maxBodySize limite do corpo para o tipo de conteúdo: o prefixo mais longo em MaxBodySizes
+// (ex: "text/plain" ou "text/"), ou MaxBodySize. Um limite <= 0 não limita o corpo.
+func maxBodySize(contentType string) int64 {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	limit, matched := config.Conf.HTTP.MaxBodySize, -1
+	for prefix, size := range config.Conf.HTTP.MaxBodySizes {
+		prefix = strings.ToLower(prefix)
+		if len(prefix) > matched && strings.HasPrefix(mediaType, prefix) {
+			limit, matched = size, len(prefix)
+		}
+	}
+	return limit
+}
+
+// memoryBudget limita a memória total reservada pelos corpos das buscas em andamento
+type memoryBudget struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	used  int64
+	limit int64
+}
+
+var bodyBudget = newMemoryBudget()
+
+func newMemoryBudget() *memoryBudget {
+	b := &memoryBudget{}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire reserva n bytes, aguardando enquanto o orçamento estiver esgotado.
+// Uma reserva maior que o orçamento inteiro é reduzida a ele. Retorna a função que libera a reserva.
+func (b *memoryBudget) acquire(n int64) func() {
+	limit := config.Conf.HTTP.MemoryBudget
+	if limit <= 0 || n <= 0 {
+		return func() {}
+	}
+	if n > limit {
+		n = limit
+	}
+	b.mu.Lock()
+	for b.used+n > limit {
+		b.cond.Wait()
+	}
+	b.used += n
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			b.used -= n
+			b.mu.Unlock()
+			b.cond.Broadcast()
+		})
+	}
+}
+
+// responseBody corpo lido de uma resposta
+type responseBody struct {
+	Data      []byte
+	Truncated bool
+	// Encoding Content-Encoding original, vazio se o corpo não era comprimido
+	Encoding       string
+	CompressedSize int64
+	// Received bytes recebidos (ainda codificados) de corpos comprimidos, guardados apenas com o WARC habilitado
+	Received []byte
+	// release libera a reserva do corpo no orçamento de memória
+	release func()
+}
+
+// readBody lê o corpo (já decodificado) da resposta até o limite do tipo de conteúdo, reservando memória
+// no orçamento global. Respostas cujo Content-Length excede o limite são abortadas sem leitura; corpos sem
+// Content-Length que excedem o limite são truncados. A reserva deve ser liberada com release após o uso do corpo.
+func readBody(resp *http.Response) (*responseBody, error) {
+	limit := maxBodySize(resp.Header.Get("Content-Type"))
+	contentLength := resp.ContentLength
+	decoded, compressed := resp.Body.(*decodedBody)
+	if compressed {
+		contentLength = decoded.CompressedLength
+	}
+	if limit > 0 && contentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrBodyTooLarge, contentLength, limit)
+	}
+
+	// O tamanho decodificado é desconhecido para corpos comprimidos, reserva-se o limite; sem limite
+	// (MaxBodySize <= 0) um corpo de tamanho desconhecido reserva unboundedBodyReserve
+	reserve := ternary.Ternary(limit > 0, limit, unboundedBodyReserve)
+	if !compressed && contentLength >= 0 && (limit <= 0 || contentLength < limit) {
+		reserve = contentLength
+	}
+	// O WARC arquiva o corpo como recebido: os bytes comprimidos também ficam em memória
+	keepReceived := compressed && warc.Enabled()
+	if keepReceived {
+		decoded.keepReceived()
+		reserve += ternary.Ternary(contentLength >= 0, contentLength, reserve)
+	}
+	body := &responseBody{release: bodyBudget.acquire(reserve)}
+
+	reader := io.Reader(resp.Body)
+	if limit > 0 {
+		reader = io.LimitReader(resp.Body, limit+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		body.release()
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		data, body.Truncated = data[:limit], true
+	}
+	body.Data = data
+	if compressed {
+		body.Encoding = decoded.Encoding
+		body.CompressedSize = decoded.CompressedSize()
+		if keepReceived {
+			body.Received = decoded.received.Bytes()
+		}
+	} else {
+		body.CompressedSize = int64(len(data))
+	}
+	return body, nil
+}
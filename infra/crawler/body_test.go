@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gabrielmoura/WebCrawler/config"
+)
+
+func TestReadBodyWithoutLimit(t *testing.T) {
+	setTestConfig(t)
+	config.Conf.HTTP.MaxBodySize = 0
+	config.Conf.HTTP.MemoryBudget = 100 << 20
+	content := strings.Repeat("x", 4096)
+	resp := &http.Response{
+		StatusCode:    http.StatusNotFound,
+		Header:        http.Header{"Content-Type": {"text/html"}},
+		ContentLength: -1,
+		Body:          io.NopCloser(strings.NewReader(content)),
+	}
+
+	body, err := readBody(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body.Data) != content || body.Truncated {
+		t.Errorf("read %d bytes (truncated %v), want %d", len(body.Data), body.Truncated, len(content))
+	}
+	// Sem limite e sem Content-Length o corpo reserva um valor fixo, não o orçamento inteiro
+	if used := bodyBudget.used; used != unboundedBodyReserve {
+		t.Errorf("memory reserved %d, want %d", used, unboundedBodyReserve)
+	}
+	body.release()
+	if used := bodyBudget.used; used != 0 {
+		t.Errorf("memory reserved after release %d", used)
+	}
+}
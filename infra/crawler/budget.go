@@ -0,0 +1,194 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBudgetExhausted = errors.New("crawl budget exhausted")
+	ErrHostQuota       = errors.New("host page quota reached")
+	// ErrBudgetReserved o orçamento restante está reservado por páginas em busca; a página volta à fila
+	ErrBudgetReserved = errors.New("crawl budget reserved by pages in progress")
+)
+
+const (
+	budgetPagesCounter = "pages"
+	budgetBytesCounter = "bytes"
+	budgetHostPrefix   = "host:"
+)
+
+// crawlBudget contadores do orçamento do crawl. Páginas e bytes são acumulados no job e gravados no cache,
+// para que a execução retomada continue de onde parou; o tempo é contado por execução.
+// Uma página é reservada antes da busca e só é cobrada quando a busca termina; adiamentos e erros devolvem a reserva.
+type crawlBudget struct {
+	mu        sync.Mutex
+	loaded    bool
+	started   time.Time
+	pages     int64
+	bytes     int64
+	hostPages map[string]int64
+	// reservas das páginas em busca
+	pending     int64
+	hostPending map[string]int64
+}
+
+var defaultBudget = &crawlBudget{hostPages: make(map[string]int64), hostPending: make(map[string]int64)}
+
+// start carrega os contadores do job e inicia a contagem do tempo da execução
+func (b *crawlBudget) start() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.load()
+	b.started = time.Now()
+}
+
+func (b *crawlBudget) load() {
+	if b.loaded {
+		return
+	}
+	b.pages = cache.GetBudgetCounter(budgetPagesCounter)
+	b.bytes = cache.GetBudgetCounter(budgetBytesCounter)
+	b.loaded = true
+}
+
+// hostCount páginas já buscadas do host, lidas do cache no primeiro uso
+func (b *crawlBudget) hostCount(host string) int64 {
+	count, ok := b.hostPages[host]
+	if !ok {
+		count = cache.GetBudgetCounter(budgetHostPrefix + host)
+		b.hostPages[host] = count
+	}
+	return count
+}
+
+// exhausted retorna o motivo do fim do orçamento (páginas, bytes ou tempo), vazio enquanto houver orçamento
+func (b *crawlBudget) exhausted() string {
+	cfg := config.Conf.Budget
+	if cfg == nil {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.load()
+	switch {
+	case cfg.MaxPages > 0 && b.pages >= cfg.MaxPages:
+		return fmt.Sprintf("max pages %d", cfg.MaxPages)
+	case cfg.MaxBytes > 0 && b.bytes >= cfg.MaxBytes:
+		return fmt.Sprintf("max bytes %d", cfg.MaxBytes)
+	case cfg.MaxDuration > 0 && !b.started.IsZero() && time.Since(b.started) >= cfg.MaxDuration:
+		return fmt.Sprintf("max duration %s", cfg.MaxDuration)
+	}
+	return ""
+}
+
+// hostExhausted verifica se o host atingiu a cota de páginas
+func (b *crawlBudget) hostExhausted(link *url.URL) bool {
+	cfg := config.Conf.Budget
+	if cfg == nil || cfg.MaxPagesPerHost <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.hostCount(strings.ToLower(link.Hostname())) >= cfg.MaxPagesPerHost
+}
+
+// reserve reserva a busca de uma página no orçamento global e na cota do host; a reserva é encerrada por settle.
+// Retorna ErrBudgetExhausted ou ErrHostQuota quando a página não deve ser buscada e ErrBudgetReserved quando
+// o orçamento restante está reservado por outras páginas em busca.
+func (b *crawlBudget) reserve(link *url.URL) error {
+	cfg := config.Conf.Budget
+	if cfg == nil {
+		return nil
+	}
+	if reason := b.exhausted(); reason != "" {
+		return fmt.Errorf("%w: %s", ErrBudgetExhausted, reason)
+	}
+	host := strings.ToLower(link.Hostname())
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	hostPages := b.hostCount(host)
+	if cfg.MaxPagesPerHost > 0 && hostPages >= cfg.MaxPagesPerHost {
+		return fmt.Errorf("%w: %s", ErrHostQuota, host)
+	}
+	if (cfg.MaxPages > 0 && b.pages+b.pending >= cfg.MaxPages) ||
+		(cfg.MaxPagesPerHost > 0 && hostPages+b.hostPending[host] >= cfg.MaxPagesPerHost) {
+		return fmt.Errorf("%w: %s", ErrBudgetReserved, host)
+	}
+	b.pending++
+	b.hostPending[host]++
+	return nil
+}
+
+// settle encerra a reserva de uma página: cobra a página buscada (charge) ou devolve a reserva
+func (b *crawlBudget) settle(link *url.URL, charge bool) {
+	if config.Conf.Budget == nil {
+		return
+	}
+	host := strings.ToLower(link.Hostname())
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending--
+	if b.hostPending[host]--; b.hostPending[host] <= 0 {
+		delete(b.hostPending, host)
+	}
+	if !charge {
+		return
+	}
+	b.pages++
+	b.hostPages[host] = b.hostCount(host) + 1
+	b.save(budgetPagesCounter, b.pages)
+	b.save(budgetHostPrefix+host, b.hostPages[host])
+}
+
+// addBytes soma os bytes recebidos ao orçamento
+func (b *crawlBudget) addBytes(n int64) {
+	if config.Conf.Budget == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.load()
+	b.bytes += n
+	b.save(budgetBytesCounter, b.bytes)
+}
+
+// holdForQuota guarda fora da fila uma página de host que atingiu a cota; ela volta à fila na próxima execução,
+// que pode ter uma cota maior (-maxPagesPerHost)
+func holdForQuota(pageUrl string, depth int) {
+	log.Logger.Debug("Host quota reached, page held", zap.String("URL", pageUrl))
+	if err := cache.HoldURL(pageUrl, depth); err != nil {
+		log.Logger.Error("error holding link", zap.String("Link", pageUrl), zap.Error(err))
+	}
+}
+
+// requeueHeld devolve à fila as páginas guardadas pela cota dos hosts; as que continuam acima da cota
+// são guardadas de novo ao sair da fila
+func requeueHeld() {
+	held, err := cache.PopHeldURLs()
+	if err != nil {
+		log.Logger.Error("error reading held links", zap.Error(err))
+		return
+	}
+	for _, page := range held {
+		if err := cache.AddToQueue(page.Url, page.Depth, queuePriority(page.Url)); err != nil {
+			log.Logger.Error("error adding link to queue", zap.String("Link", page.Url), zap.Error(err))
+		}
+	}
+}
+
+func (b *crawlBudget) save(name string, value int64) {
+	if err := cache.SetBudgetCounter(name, value); err != nil {
+		log.Logger.Error("error saving budget", zap.String("Counter", name), zap.Error(err))
+	}
+}
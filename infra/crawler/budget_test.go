@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/gabrielmoura/WebCrawler/config"
+)
+
+func TestBudgetChargesOnlyFetchedPages(t *testing.T) {
+	setTestConfig(t)
+	setTestCache(t)
+	config.Conf.Budget = &config.Budget{MaxPagesPerHost: 1}
+	budget := &crawlBudget{hostPages: make(map[string]int64), hostPending: make(map[string]int64)}
+	link, _ := url.Parse("http://budget.example/page")
+
+	if err := budget.reserve(link); err != nil {
+		t.Fatalf("first reserve: %v", err)
+	}
+	if err := budget.reserve(link); !errors.Is(err, ErrBudgetReserved) {
+		t.Fatalf("reserve while in progress: %v, want %v", err, ErrBudgetReserved)
+	}
+	// Adiamento ou erro: a reserva é devolvida
+	budget.settle(link, false)
+	if budget.hostExhausted(link) {
+		t.Fatal("refunded page counted in the host quota")
+	}
+	if err := budget.reserve(link); err != nil {
+		t.Fatalf("reserve after refund: %v", err)
+	}
+	budget.settle(link, true)
+	if err := budget.reserve(link); !errors.Is(err, ErrHostQuota) {
+		t.Fatalf("reserve after charge: %v, want %v", err, ErrHostQuota)
+	}
+	if budget.pages != 1 || budget.pending != 0 {
+		t.Errorf("pages %d pending %d, want 1 and 0", budget.pages, budget.pending)
+	}
+}
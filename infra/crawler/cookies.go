@@ -0,0 +1,181 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+	"golang.org/x/net/publicsuffix"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// persistentJar cookie jar que grava no cache os cookies recebidos, restaurando-os na próxima execução
+type persistentJar struct {
+	name string
+	jar  *cookiejar.Jar
+}
+
+func (j *persistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+func (j *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+	now := time.Now()
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+	for _, c := range cookies {
+		stored := *c
+		// Max-Age é relativo ao recebimento, guarda-se o horário absoluto
+		if stored.MaxAge > 0 {
+			stored.Expires = now.Add(time.Duration(stored.MaxAge) * time.Second)
+			stored.MaxAge = 0
+		}
+		key := cookieStoreKey(u, c)
+		if c.MaxAge < 0 || (!stored.Expires.IsZero() && stored.Expires.Before(now)) {
+			if err := cache.DeleteCookie(j.name, key); err != nil {
+				log.Logger.Debug("error deleting cookie", zap.String("Jar", j.name), zap.Error(err))
+			}
+			continue
+		}
+		if err := cache.SetCookie(j.name, key, cache.StoredCookie{URL: origin, Cookie: &stored}); err != nil {
+			log.Logger.Error("error saving cookie", zap.String("Jar", j.name), zap.Error(err))
+		}
+	}
+}
+
+// cookieStoreKey identifica o cookie como o jar faz: domínio, caminho e nome
+func cookieStoreKey(u *url.URL, c *http.Cookie) string {
+	domain := strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+	if domain == "" {
+		domain = strings.ToLower(u.Hostname())
+	}
+	return domain + "|" + c.Path + "|" + c.Name
+}
+
+// load restaura os cookies persistidos que ainda não expiraram
+func (j *persistentJar) load() {
+	stored, err := cache.LoadCookies(j.name)
+	if err != nil {
+		log.Logger.Error("error loading cookies", zap.String("Jar", j.name), zap.Error(err))
+		return
+	}
+	now := time.Now()
+	for _, s := range stored {
+		if s.Cookie == nil || (!s.Cookie.Expires.IsZero() && s.Cookie.Expires.Before(now)) {
+			continue
+		}
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			continue
+		}
+		j.jar.SetCookies(u, []*http.Cookie{s.Cookie})
+	}
+}
+
+var (
+	jarsMu sync.Mutex
+	jars   = make(map[string]*persistentJar)
+)
+
+// cookieJarFor retorna o cookie jar da rota: um jar compartilhado ou, com Isolate, um por rota de proxy.
+// Retorna nil quando os cookies estão desabilitados e nenhum domínio usa login por formulário.
+func cookieJarFor(route proxyRoute) http.CookieJar {
+	cfg := config.Conf.Cookies
+	if cfg == nil {
+		cfg = &config.Cookies{}
+	}
+	if !cfg.Enabled && !hasFormAuth() {
+		return nil
+	}
+	name := "default"
+	if cfg.Isolate {
+		name = "route:" + route.name
+	}
+
+	jarsMu.Lock()
+	defer jarsMu.Unlock()
+	if jar, ok := jars[name]; ok {
+		return jar
+	}
+	inner, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	jar := &persistentJar{name: name, jar: inner}
+	jar.load()
+	if cfg.File != "" {
+		importCookies(jar, cfg.File, func(u *url.URL) bool {
+			return !cfg.Isolate || routeFor(u).name == route.name
+		})
+	}
+	jars[name] = jar
+	return jar
+}
+
+// importCookies adiciona ao jar os cookies de um arquivo cookies.txt (formato Netscape)
+// cujos domínios pertencem à rota (match)
+func importCookies(jar *persistentJar, path string, match func(*url.URL) bool) {
+	cookies, err := readCookiesFile(path)
+	if err != nil {
+		log.Logger.Error("error reading cookies file", zap.String("File", path), zap.Error(err))
+		return
+	}
+	for _, c := range cookies {
+		if match(c.url) {
+			jar.jar.SetCookies(c.url, []*http.Cookie{c.cookie})
+		}
+	}
+}
+
+type fileCookie struct {
+	url    *url.URL
+	cookie *http.Cookie
+}
+
+// readCookiesFile lê um arquivo cookies.txt: domínio, subdomínios, caminho, secure, expiração, nome e valor
+// separados por tab; linhas com o prefixo #HttpOnly_ são cookies HttpOnly
+func readCookiesFile(path string) ([]fileCookie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cookies []fileCookie
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(text, "#HttpOnly_")
+		if httpOnly {
+			text = strings.TrimPrefix(text, "#HttpOnly_")
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("invalid cookie at line %d", line)
+		}
+		domain, subdomains, path, secure := fields[0], fields[1] == "TRUE", fields[2], fields[3] == "TRUE"
+		host := strings.TrimPrefix(domain, ".")
+		cookie := &http.Cookie{Name: fields[5], Value: fields[6], Path: path, Secure: secure, HttpOnly: httpOnly}
+		if subdomains {
+			cookie.Domain = host
+		}
+		if expires, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		cookies = append(cookies, fileCookie{url: &url.URL{Scheme: scheme, Host: host, Path: path}, cookie: cookie})
+	}
+	return cookies, scanner.Err()
+}
@@ -1,10 +1,14 @@
 package crawler
 
 import (
+	"github.com/gabrielmoura/WebCrawler/config"
 	"github.com/gabrielmoura/WebCrawler/infra/cache"
 	"github.com/gabrielmoura/WebCrawler/infra/data"
 	"github.com/gabrielmoura/WebCrawler/infra/db"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
 	"sync"
+	"time"
 )
 
 var visitedMutex sync.Mutex
@@ -18,9 +22,15 @@ func SetVisited(url string) {
 }
 
 // GetVisited retrieves a URL from the cache to check if it has been visited.
+// With Recrawl enabled a page whose next visit is due counts as not visited.
 func GetVisited(url string) bool {
 	visitedMutex.Lock()
 	defer visitedMutex.Unlock()
+	if config.Conf.Recrawl.Enabled {
+		if state, ok := cache.GetFetchState(url); ok && !state.NextVisit.IsZero() && !state.NextVisit.After(time.Now()) {
+			return false
+		}
+	}
 	return cache.IsVisited(url)
 }
 
@@ -35,6 +45,14 @@ func SetPage(url string, page *data.Page) {
 	//pagesMutex.Unlock()
 }
 
+// SetLinks replaces the outgoing links of a page in the link graph.
+func SetLinks(url string, links []data.Link) {
+	err := db.WriteLinks(url, links)
+	if err != nil {
+		log.Logger.Error("error writing links", zap.String("URL", url), zap.Error(err))
+	}
+}
+
 // GetPage retrieves a page from the database.
 func GetPage(url string) *data.Page {
 	//pagesMutex.Lock()
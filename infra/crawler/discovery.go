@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/base32"
+	"golang.org/x/crypto/sha3"
+	"golang.org/x/net/html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// onionRegex endereços onion v3 (56 caracteres base32), com subdomínios opcionais
+	onionRegex = regexp.MustCompile(`(?i)\b(?:[a-z0-9-]+\.)*[a-z2-7]{56}\.onion\b`)
+	// i2pRegex nomes .i2p, incluindo endereços .b32.i2p
+	i2pRegex = regexp.MustCompile(`(?i)\b(?:[a-z0-9-]+\.)+i2p\b`)
+)
+
+const onionV3Version = 0x03
+
+// isValidOnionV3 valida o checksum de um endereço onion v3:
+// base32(pubkey(32) | checksum(2) | versão(1)), checksum = SHA3-256(".onion checksum" | pubkey | versão)[:2]
+func isValidOnionV3(host string) bool {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(host), ".onion"), ".")
+	raw, err := base32.StdEncoding.DecodeString(strings.ToUpper(labels[len(labels)-1]))
+	if err != nil || len(raw) != 35 || raw[34] != onionV3Version {
+		return false
+	}
+	pubKey, checksum := raw[:32], raw[32:34]
+	h := sha3.New256()
+	h.Write([]byte(".onion checksum"))
+	h.Write(pubKey)
+	h.Write([]byte{onionV3Version})
+	return bytes.Equal(h.Sum(nil)[:2], checksum)
+}
+
+// isValidI2P valida um nome .i2p; endereços .b32.i2p precisam de ao menos 52 caracteres base32
+func isValidI2P(host string) bool {
+	host = strings.ToLower(host)
+	if !isB32Host(host) {
+		return true
+	}
+	label := strings.TrimSuffix(host, ".b32.i2p")
+	if len(label) < 52 || strings.Contains(label, ".") {
+		return false
+	}
+	return strings.Trim(label, "abcdefghijklmnopqrstuvwxyz234567") == ""
+}
+
+// findHiddenServices procura hosts .onion v3 e .i2p no texto, retornando-os em minúsculas
+func findHiddenServices(text string) []string {
+	var hosts []string
+	for _, host := range onionRegex.FindAllString(text, -1) {
+		if isValidOnionV3(host) {
+			hosts = append(hosts, strings.ToLower(host))
+		}
+	}
+	for _, host := range i2pRegex.FindAllString(text, -1) {
+		if isValidI2P(host) {
+			hosts = append(hosts, strings.ToLower(host))
+		}
+	}
+	return hosts
+}
+
+// extractHiddenServices descobre endereços .onion e .i2p no texto visível e nos atributos da página,
+// inclusive os que aparecem apenas como texto, e os normaliza como URLs http://host/
+func extractHiddenServices(n *html.Node, plainText []byte) []string {
+	seen := make(map[string]struct{})
+	var urls []string
+	add := func(text string) {
+		for _, host := range findHiddenServices(text) {
+			if _, ok := seen[host]; ok {
+				continue
+			}
+			seen[host] = struct{}{}
+			urls = append(urls, "http://"+host+"/")
+		}
+	}
+
+	add(string(plainText))
+	var extract func(*html.Node)
+	extract = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, a := range n.Attr {
+				add(a.Val)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			extract(c)
+		}
+	}
+	extract(n)
+	return urls
+}
@@ -0,0 +1,244 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// acceptEncoding codificações anunciadas e decodificadas pelo crawler
+const acceptEncoding = "gzip, deflate, br, zstd"
+
+// countingReader conta os bytes lidos, usado para o tamanho comprimido do corpo
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// decodedBody corpo decodificado, com a codificação original e o tamanho comprimido lido.
+// Os decodificadores são criados na primeira leitura, como o gzipReader do net/http: um corpo vazio
+// resulta em EOF, e não em erro.
+type decodedBody struct {
+	// Reader decodificadores aplicados, nil até a primeira leitura
+	io.Reader
+	raw     *countingReader
+	codings []string
+	closers []io.Closer
+	// Encoding valor original de Content-Encoding
+	Encoding string
+	// CompressedLength Content-Length original (comprimido), -1 se desconhecido
+	CompressedLength int64
+	// received bytes recebidos (ainda codificados), nil se não são guardados
+	received *bytes.Buffer
+}
+
+// keepReceived guarda os bytes recebidos, antes da decodificação, para o arquivamento no WARC.
+// Deve ser chamado antes da primeira leitura.
+func (b *decodedBody) keepReceived() {
+	b.received = &bytes.Buffer{}
+	b.raw.r = io.TeeReader(b.raw.r, b.received)
+}
+
+// receivedHeader cabeçalho da resposta como recebido, com Content-Encoding e Content-Length originais
+func (b *decodedBody) receivedHeader(header http.Header) http.Header {
+	header = header.Clone()
+	header.Set("Content-Encoding", b.Encoding)
+	if b.CompressedLength >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(b.CompressedLength, 10))
+	}
+	return header
+}
+
+// CompressedSize bytes comprimidos lidos até o momento
+func (b *decodedBody) CompressedSize() int64 {
+	return b.raw.n
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.Reader == nil {
+		b.Reader = b.open()
+	}
+	return b.Reader.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if cerr := b.closers[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// errReader leitor que sempre retorna o erro da criação do decodificador
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// decodeError mantém io.EOF (corpo vazio) sem alteração, para que io.ReadAll o trate como fim do corpo
+func decodeError(coding string, err error) io.Reader {
+	if err == io.EOF {
+		return errReader{err: err}
+	}
+	return errReader{err: fmt.Errorf("error decoding %s: %w", coding, err)}
+}
+
+// open aplica os decodificadores na ordem inversa da lista de Content-Encoding
+func (b *decodedBody) open() io.Reader {
+	var reader io.Reader = b.raw
+	for i := len(b.codings) - 1; i >= 0; i-- {
+		switch coding := b.codings[i]; coding {
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(reader)
+			if err != nil {
+				return decodeError(coding, err)
+			}
+			b.closers = append(b.closers, zr)
+			reader = zr
+		case "deflate":
+			zr, err := newDeflateReader(reader)
+			if err != nil {
+				return decodeError(coding, err)
+			}
+			b.closers = append(b.closers, zr)
+			reader = zr
+		case "br":
+			reader = brotli.NewReader(reader)
+		case "zstd":
+			zr, err := zstd.NewReader(reader, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return decodeError(coding, err)
+			}
+			closer := zr.IOReadCloser()
+			b.closers = append(b.closers, closer)
+			reader = closer
+		}
+	}
+	return reader
+}
+
+// decodingTransport anuncia gzip, deflate, br e zstd e decodifica a resposta de forma transparente,
+// como o http.Transport faz apenas para gzip quando ele mesmo define Accept-Encoding.
+// Respostas sem corpo (1xx, 204, 304 ou vazias) e com codificação desconhecida seguem sem decodificação.
+type decodingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || req.Method == http.MethodHead || !hasBody(resp) {
+		return resp, err
+	}
+	encoding := strings.TrimSpace(resp.Header.Get("Content-Encoding"))
+	if encoding == "" || strings.EqualFold(encoding, "identity") {
+		return resp, nil
+	}
+	body, err := newDecodedBody(resp.Body, encoding)
+	if err != nil {
+		// Mantém Content-Encoding para que o crawler arquive a resposta e descarte o corpo
+		return resp, nil
+	}
+	body.CompressedLength = resp.ContentLength
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// hasBody verifica se a resposta pode ter corpo: 1xx, 204 e 304 não têm, e Content-Length 0 indica corpo vazio
+func hasBody(resp *http.Response) bool {
+	if resp.StatusCode < http.StatusOK || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return false
+	}
+	return resp.ContentLength != 0 && resp.Body != nil && resp.Body != http.NoBody
+}
+
+// undecodedEncoding retorna o Content-Encoding de um corpo que seguiu sem decodificação por ter
+// codificação desconhecida, vazio se o corpo pode ser interpretado
+func undecodedEncoding(resp *http.Response) string {
+	encoding := strings.TrimSpace(resp.Header.Get("Content-Encoding"))
+	if _, decoded := resp.Body.(*decodedBody); decoded || !hasBody(resp) || strings.EqualFold(encoding, "identity") {
+		return ""
+	}
+	return encoding
+}
+
+// newDecodedBody valida a lista de Content-Encoding; os decodificadores são criados na primeira leitura
+func newDecodedBody(body io.ReadCloser, encoding string) (*decodedBody, error) {
+	var codings []string
+	for _, coding := range strings.Split(encoding, ",") {
+		switch coding = strings.ToLower(strings.TrimSpace(coding)); coding {
+		case "gzip", "x-gzip", "deflate", "br", "zstd":
+			codings = append(codings, coding)
+		case "identity", "":
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, coding)
+		}
+	}
+	return &decodedBody{
+		raw:              &countingReader{r: body},
+		codings:          codings,
+		closers:          []io.Closer{body},
+		Encoding:         encoding,
+		CompressedLength: -1,
+	}, nil
+}
+
+// decodeReceived decodifica um corpo arquivado como recebido, conforme o Content-Encoding do cabeçalho.
+// Corpos truncados (WARC-Truncated) são aceitos decodificados até onde for possível.
+func decodeReceived(header http.Header, body []byte, truncated bool) ([]byte, error) {
+	encoding := strings.TrimSpace(header.Get("Content-Encoding"))
+	if encoding == "" || strings.EqualFold(encoding, "identity") || len(body) == 0 {
+		return body, nil
+	}
+	decoded, err := newDecodedBody(io.NopCloser(bytes.NewReader(body)), encoding)
+	if err != nil {
+		return nil, err
+	}
+	defer decoded.Close()
+	data, err := io.ReadAll(decoded)
+	if err != nil && !(truncated && len(data) > 0) {
+		return nil, err
+	}
+	return data, nil
+}
+
+// newDeflateReader lê deflate com cabeçalho zlib (RFC 9110) ou, como alguns servidores enviam, deflate puro
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,201 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func encodeGzip(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeZlib(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, _ = w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeFlate(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	_, _ = w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeBrotli(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := brotli.NewWriter(&buf)
+	_, _ = w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeZstd(t *testing.T, data []byte) []byte {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, nil)
+}
+
+func TestDecodingTransport(t *testing.T) {
+	setTestConfig(t)
+	page := []byte(strings.Repeat("<p>Olá, página comprimida</p>\n", 64))
+
+	tests := []struct {
+		name     string
+		status   int
+		encoding string
+		raw      []byte
+		// body e encoding esperados após a decodificação
+		body    []byte
+		decoded string
+		// undecoded codificação desconhecida, corpo entregue como recebido
+		undecoded bool
+		// chunked envia o corpo sem Content-Length
+		chunked bool
+	}{
+		{name: "identity", status: http.StatusOK, raw: page, body: page},
+		{name: "gzip", status: http.StatusOK, encoding: "gzip", raw: encodeGzip(t, page), body: page, decoded: "gzip"},
+		{name: "x-gzip", status: http.StatusOK, encoding: "x-gzip", raw: encodeGzip(t, page), body: page, decoded: "x-gzip"},
+		{name: "deflate zlib", status: http.StatusOK, encoding: "deflate", raw: encodeZlib(t, page), body: page, decoded: "deflate"},
+		{name: "deflate raw", status: http.StatusOK, encoding: "deflate", raw: encodeFlate(t, page), body: page, decoded: "deflate"},
+		{name: "br", status: http.StatusOK, encoding: "br", raw: encodeBrotli(t, page), body: page, decoded: "br"},
+		{name: "zstd", status: http.StatusOK, encoding: "zstd", raw: encodeZstd(t, page), body: page, decoded: "zstd"},
+		{name: "gzip then br", status: http.StatusOK, encoding: "gzip, br", raw: encodeBrotli(t, encodeGzip(t, page)), body: page, decoded: "gzip, br"},
+		{name: "deflate then zstd", status: http.StatusOK, encoding: "deflate, zstd", raw: encodeZstd(t, encodeZlib(t, page)), body: page, decoded: "deflate, zstd"},
+		{name: "empty gzip", status: http.StatusOK, encoding: "gzip", raw: []byte{}, body: []byte{}},
+		{name: "empty gzip chunked", status: http.StatusOK, encoding: "gzip", raw: []byte{}, body: []byte{}, decoded: "gzip", chunked: true},
+		{name: "redirect gzip", status: http.StatusMovedPermanently, encoding: "gzip", raw: []byte{}, body: []byte{}},
+		{name: "no content gzip", status: http.StatusNoContent, encoding: "gzip", body: []byte{}},
+		{name: "not modified gzip", status: http.StatusNotModified, encoding: "gzip", body: []byte{}},
+		{name: "unknown coding", status: http.StatusOK, encoding: "compress", raw: []byte("LZW"), body: []byte("LZW"), undecoded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != acceptEncoding {
+					t.Errorf("Accept-Encoding %q, want %q", got, acceptEncoding)
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				if tt.status == http.StatusMovedPermanently {
+					w.Header().Set("Location", "/next")
+				}
+				w.WriteHeader(tt.status)
+				if tt.chunked {
+					w.(http.Flusher).Flush()
+				}
+				_, _ = w.Write(tt.raw)
+			}))
+			defer srv.Close()
+
+			client := &http.Client{
+				Transport:     &decodingTransport{base: newTransport(proxyRoute{}, nil)},
+				CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+			}
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			body, err := readBody(resp)
+			if err != nil {
+				t.Fatalf("readBody: %v", err)
+			}
+			defer body.release()
+			if !bytes.Equal(body.Data, tt.body) {
+				t.Errorf("body %q, want %q", body.Data, tt.body)
+			}
+			if body.Encoding != tt.decoded {
+				t.Errorf("Encoding %q, want %q", body.Encoding, tt.decoded)
+			}
+			if body.CompressedSize != int64(len(tt.raw)) {
+				t.Errorf("CompressedSize %d, want %d", body.CompressedSize, len(tt.raw))
+			}
+			if got := undecodedEncoding(resp); (got != "") != tt.undecoded {
+				t.Errorf("undecoded encoding %q", got)
+			}
+		})
+	}
+}
+
+func TestDecodedBodyCorrupt(t *testing.T) {
+	body, err := newDecodedBody(io.NopCloser(strings.NewReader("not gzip")), "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(body); err == nil || !strings.Contains(err.Error(), "gzip") {
+		t.Errorf("corrupt gzip error %v", err)
+	}
+}
+
+func TestDecodedBodyReceived(t *testing.T) {
+	page := []byte(strings.Repeat("<p>Olá, página arquivada</p>\n", 64))
+	raw := encodeGzip(t, page)
+
+	body, err := newDecodedBody(io.NopCloser(bytes.NewReader(raw)), "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.CompressedLength = int64(len(raw))
+	body.keepReceived()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, page) {
+		t.Errorf("decoded body %q", data)
+	}
+	if !bytes.Equal(body.received.Bytes(), raw) {
+		t.Errorf("received %d bytes, want the %d bytes sent", body.received.Len(), len(raw))
+	}
+
+	header := body.receivedHeader(http.Header{"Content-Type": {"text/html"}})
+	if header.Get("Content-Encoding") != "gzip" || header.Get("Content-Length") != strconv.Itoa(len(raw)) {
+		t.Errorf("received header %v", header)
+	}
+	if got, err := decodeReceived(header, raw, false); err != nil || !bytes.Equal(got, page) {
+		t.Errorf("decodeReceived = %q, %v", got, err)
+	}
+	// Corpo truncado: decodificado até onde for possível apenas com WARC-Truncated
+	if _, err := decodeReceived(header, raw[:len(raw)/2], false); err == nil {
+		t.Error("truncated body decoded without WARC-Truncated")
+	}
+	if got, err := decodeReceived(header, raw[:len(raw)/2], true); err != nil || len(got) == 0 || !bytes.HasPrefix(page, got) {
+		t.Errorf("truncated decodeReceived = %d bytes, %v", len(got), err)
+	}
+}
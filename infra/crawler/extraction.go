@@ -7,6 +7,7 @@ import (
 	"github.com/gabrielmoura/WebCrawler/config"
 	"github.com/gabrielmoura/WebCrawler/infra/data"
 	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"github.com/gabrielmoura/go/pkg/ternary"
 	"go.uber.org/zap"
 	"golang.org/x/net/html"
 	"regexp"
@@ -15,30 +16,47 @@ import (
 
 var InvalidMeta = errors.New("invalid meta")
 
-// countWordsInText Extrai e conta a frequência de palavras do conteúdo HTML, ignorando palavras irrelevantes comuns.
-func countWordsInText(data []byte) (map[string]int, error) {
-	log.Logger.Debug("Word Count")
+// extractPlainText Remove scripts, estilos e tags HTML, retornando apenas o texto visível.
+func extractPlainText(data []byte) []byte {
 	// Etapa 1: Ignorar determinadas tags HTML
-	htmlRegex := regexp.MustCompile("(?s)<(script|style|noscript|link|meta)[^>]*?>.*?</(script|style|noscript|link|meta)>")
-	parcialPlainText := htmlRegex.ReplaceAll(data, []byte(""))
+	parcialPlainText := hiddenTagsRegex.ReplaceAll(data, []byte(""))
 
 	// Etapa 2: remover tags HTML
-	tagsRegex := regexp.MustCompile("<([^>]*)>")
-	plainText := tagsRegex.ReplaceAll(parcialPlainText, []byte(""))
+	return tagsRegex.ReplaceAll(parcialPlainText, []byte(""))
+}
 
+var (
+	hiddenTagsRegex = regexp.MustCompile("(?s)<(script|style|noscript|link|meta)[^>]*?>.*?</(script|style|noscript|link|meta)>")
+	tagsRegex       = regexp.MustCompile("<([^>]*)>")
+)
+
+// splitWords Normaliza o texto e o divide em palavras.
+func splitWords(plainText []byte) []string {
 	// Etapa 3: Normalizar texto
 	normalizedText := bytes.ToLower(plainText)
 
 	// Etapa 4: Remova caracteres especiais e divida em palavras
 	wordRegex := regexp.MustCompile("[^\\pL\\pN\\pZ'-]+")
 	noSpecialCh := wordRegex.ReplaceAll(normalizedText, []byte(" "))
-	words := bytes.Split(noSpecialCh, []byte(" "))
 
-	// Etapa 5: Conte a frequência das palavras (ignorando palavras comuns)
-	wordCounts := make(map[string]int)
-	for _, wordBytes := range words {
+	var words []string
+	for _, wordBytes := range bytes.Split(noSpecialCh, []byte(" ")) {
 		word := string(bytes.TrimSpace(wordBytes))
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
 
+// countWordsInText Conta a frequência de palavras do texto visível (ver extractPlainText), ignorando palavras irrelevantes comuns.
+func countWordsInText(plainText []byte) (map[string]int, error) {
+	log.Logger.Debug("Word Count")
+	words := splitWords(plainText)
+
+	// Etapa 5: Conte a frequência das palavras (ignorando palavras comuns)
+	wordCounts := make(map[string]int)
+	for _, word := range words {
 		// Pule palavras curtas e palavras de parada comuns
 		if len(word) < 2 || containsMap(config.CommonStopWords, word) {
 			continue
@@ -193,28 +211,48 @@ func extractTitle(n *html.Node, dataPage *data.Page) {
 	}
 }
 
-// extractLinks Extrai links de um documento HTML.
-func extractLinks(parentLink string, n *html.Node) ([]string, error) {
-	var links []string
+// extractLinks Extrai links de um documento HTML a partir das fontes configuradas (config.Conf.LinkSources),
+// com texto âncora, atributo rel e o tipo do link (página ou recurso).
+func extractLinks(parentLink string, n *html.Node) ([]data.Link, error) {
+	sources := make(map[string][]config.LinkSource)
+	for _, source := range config.Conf.LinkSources {
+		element := strings.ToLower(source.Element)
+		sources[element] = append(sources[element], source)
+	}
+	var links []data.Link
 
 	var extract func(*html.Node)
 	extract = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" {
-			for _, a := range n.Attr {
-				if a.Key == "href" {
-					urlE, err := prepareLink(a.Val)
+		if n.Type == html.ElementNode {
+			for _, source := range sources[n.Data] {
+				linkType := ternary.Ternary(source.Type == data.LinkTypeResource, data.LinkTypeResource, data.LinkTypePage)
+				for _, href := range linkSourceValues(n, source) {
+					urlE, err := prepareLink(href)
+					if errors.Is(err, invalidSchemaErr) {
+						urlE, err = prepareParentLink(parentLink, href)
+					}
+					if errors.Is(err, ErrDenySuffix) && linkType == data.LinkTypeResource {
+						err = nil
+					}
 					if err != nil {
-						if errors.Is(invalidSchemaErr, err) {
-							preparedLink, err := prepareParentLink(parentLink, a.Val)
-							if err != nil {
-								continue
-							}
-							urlE = preparedLink
-						}
 						log.Logger.Debug(fmt.Sprintf("Error preparing link: %s", err))
 						continue
 					}
-					links = append(links, urlE.String())
+					if !isAllowedSchema(urlE.String(), config.AcceptableSchema) {
+						continue
+					}
+					rel, _ := getAttr(n, "rel")
+					links = append(links, data.Link{
+						Source:   parentLink,
+						Target:   urlE.String(),
+						Anchor:   extractLinkText(n),
+						Rel:      rel,
+						NoFollow: hasRel(rel, "nofollow"),
+						Element:  n.Data,
+						Attr:     source.Attr,
+						Type:     linkType,
+						Position: len(links),
+					})
 				}
 			}
 		}
@@ -226,6 +264,139 @@ func extractLinks(parentLink string, n *html.Node) ([]string, error) {
 	extract(n)
 	return links, nil
 }
+
+// getAttr retorna o valor do atributo do elemento
+func getAttr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// linkSourceValues retorna as URLs do atributo da fonte no elemento.
+// Formulários só contam com método GET, meta só com http-equiv=refresh e srcset pode conter várias URLs.
+func linkSourceValues(n *html.Node, source config.LinkSource) []string {
+	val, ok := getAttr(n, source.Attr)
+	val = strings.TrimSpace(val)
+	if !ok || val == "" {
+		return nil
+	}
+	if len(source.Rel) > 0 {
+		rel, _ := getAttr(n, "rel")
+		matched := false
+		for _, r := range source.Rel {
+			if hasRel(rel, strings.ToLower(r)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil
+		}
+	}
+
+	switch {
+	case n.Data == "form":
+		if method, _ := getAttr(n, "method"); method != "" && !strings.EqualFold(method, "get") {
+			return nil
+		}
+	case n.Data == "meta":
+		if equiv, _ := getAttr(n, "http-equiv"); !strings.EqualFold(equiv, "refresh") {
+			return nil
+		}
+		if target := refreshURL(val); target != "" {
+			return []string{target}
+		}
+		return nil
+	case strings.HasSuffix(source.Attr, "srcset"):
+		return parseSrcset(val)
+	}
+	return []string{val}
+}
+
+// refreshURL extrai a URL do conteúdo de <meta http-equiv="refresh">, ex: "5; url=/nova"
+func refreshURL(content string) string {
+	_, rest, ok := strings.Cut(content, ";")
+	if !ok {
+		return ""
+	}
+	rest = strings.TrimSpace(rest)
+	if len(rest) >= 4 && strings.EqualFold(rest[:4], "url=") {
+		rest = rest[4:]
+	}
+	return strings.Trim(strings.TrimSpace(rest), `"'`)
+}
+
+// parseSrcset extrai as URLs de um atributo srcset, ex: "a.png 1x, b.png 2x"
+func parseSrcset(srcset string) []string {
+	var urls []string
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// extractLinkText texto do link: conteúdo de <a>, ou o atributo alt/title dos demais elementos
+func extractLinkText(n *html.Node) string {
+	if n.Data == "a" {
+		return extractAnchorText(n)
+	}
+	if alt, ok := getAttr(n, "alt"); ok {
+		return strings.Join(strings.Fields(alt), " ")
+	}
+	title, _ := getAttr(n, "title")
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// extractAnchorText retorna o texto visível do elemento, com espaços normalizados
+func extractAnchorText(n *html.Node) string {
+	var text strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			text.WriteString(n.Data)
+			text.WriteString(" ")
+		case n.Type == html.ElementNode && n.Data == "img":
+			for _, a := range n.Attr {
+				if a.Key == "alt" {
+					text.WriteString(a.Val)
+					text.WriteString(" ")
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+// hasRel verifica se o atributo rel contém o valor informado
+func hasRel(rel, value string) bool {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		if r == value {
+			return true
+		}
+	}
+	return false
+}
+
+// linkTargets retorna as URLs de destino dos links de página, recursos não são seguidos
+func linkTargets(links []data.Link) []string {
+	targets := make([]string, 0, len(links))
+	for _, link := range links {
+		if link.Type == data.LinkTypePage {
+			targets = append(targets, link.Target)
+		}
+	}
+	return targets
+}
 func extractJSONLD(n *html.Node, dataPage *data.Page) {
 	for _, a := range n.Attr {
 		if a.Key == "type" && a.Val == "application/ld+json" {
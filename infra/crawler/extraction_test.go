@@ -0,0 +1,26 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/spf13/viper"
+)
+
+func TestExampleConfigLinkSources(t *testing.T) {
+	vip := viper.New()
+	vip.SetConfigFile("../../example_config.yml")
+	if err := vip.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		LinkSources []config.LinkSource `mapstructure:"LINK_SOURCES"`
+	}
+	if err := vip.Unmarshal(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.LinkSources, config.DefaultLinkSources) {
+		t.Errorf("example_config.yml LINK_SOURCES differ from DefaultLinkSources:\n%+v\n%+v", cfg.LinkSources, config.DefaultLinkSources)
+	}
+}
@@ -0,0 +1,179 @@
+package crawler
+
+import (
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	userAgentRoundRobin = "round-robin"
+	userAgentRandom     = "random"
+	userAgentPerHost    = "per-host"
+)
+
+// Políticas de Referer, com a mesma semântica do cabeçalho Referrer-Policy dos navegadores
+const (
+	RefererNoReferer                   = "no-referrer"
+	RefererOrigin                      = "origin"
+	RefererSameOrigin                  = "same-origin"
+	RefererStrictOriginWhenCrossOrigin = "strict-origin-when-cross-origin"
+	RefererUnsafeURL                   = "unsafe-url"
+)
+
+var userAgentNext atomic.Uint64
+
+// domainHeadersFor retorna os cabeçalhos do domínio mais específico que contém o host
+func domainHeadersFor(link *url.URL) *config.DomainHeaders {
+	cfg := config.Conf.Headers
+	if cfg == nil {
+		return nil
+	}
+	host := strings.ToLower(link.Hostname())
+	var chosen *config.DomainHeaders
+	matched := -1
+	for i := range cfg.Domains {
+		domain := strings.TrimPrefix(strings.ToLower(cfg.Domains[i].Domain), ".")
+		if len(domain) > matched && hostMatches(host, domain) {
+			chosen, matched = &cfg.Domains[i], len(domain)
+		}
+	}
+	return chosen
+}
+
+// applyHeaders define User-Agent e os cabeçalhos globais e do domínio, sem sobrescrever
+// os cabeçalhos já definidos para a requisição (ex: condicionais e Referer)
+func applyHeaders(req *http.Request) {
+	preset := req.Header.Clone()
+	cfg := config.Conf.Headers
+	// O domínio e o User-Agent por host seguem o cabeçalho Host, quando definido
+	link := originURL(req)
+	domain := domainHeadersFor(link)
+
+	req.Header.Set("User-Agent", userAgentFor(link, domain))
+	if cfg != nil {
+		for name, value := range cfg.Global {
+			req.Header.Set(name, value)
+		}
+	}
+	if domain != nil {
+		for name, value := range domain.Headers {
+			req.Header.Set(name, value)
+		}
+	}
+	for name, values := range preset {
+		req.Header[name] = values
+	}
+}
+
+// userAgentFor escolhe o User-Agent do pool do domínio, ou do global, pela estratégia configurada.
+// Sem pool é usado USER_AGENT.
+func userAgentFor(link *url.URL, domain *config.DomainHeaders) string {
+	var agents []string
+	strategy := userAgentRoundRobin
+	if cfg := config.Conf.Headers; cfg != nil {
+		agents = cfg.UserAgents
+		if cfg.UserAgentStrategy != "" {
+			strategy = cfg.UserAgentStrategy
+		}
+	}
+	if domain != nil && len(domain.UserAgents) > 0 {
+		agents = domain.UserAgents
+	}
+	if len(agents) == 0 {
+		return config.Conf.UserAgent
+	}
+	switch strategy {
+	case userAgentRandom:
+		return agents[rand.Intn(len(agents))]
+	case userAgentPerHost:
+		// O mesmo host recebe sempre o mesmo User-Agent
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(strings.ToLower(link.Hostname())))
+		return agents[h.Sum32()%uint32(len(agents))]
+	default:
+		return agents[(userAgentNext.Add(1)-1)%uint64(len(agents))]
+	}
+}
+
+// refererPolicyFor política de Referer do domínio de destino, ou a global
+func refererPolicyFor(link *url.URL) string {
+	if domain := domainHeadersFor(link); domain != nil && domain.RefererPolicy != "" {
+		return domain.RefererPolicy
+	}
+	if cfg := config.Conf.Headers; cfg != nil {
+		return cfg.RefererPolicy
+	}
+	return RefererNoReferer
+}
+
+// setReferer registra a página de origem de um link enfileirado, para o Referer quando ele for buscado.
+// Apenas o primeiro referenciador de cada URL é mantido.
+func setReferer(link, referer string) {
+	if cfg := config.Conf.Headers; referer == "" || cfg == nil || (cfg.RefererPolicy == RefererNoReferer && len(cfg.Domains) == 0) {
+		return
+	}
+	if err := cache.SetReferer(link, referer); err != nil {
+		log.Logger.Error("error setting referer", zap.String("URL", link), zap.Error(err))
+	}
+}
+
+// withReferer adiciona o Referer da página que levou à URL, conforme a política do domínio
+func withReferer(header http.Header, pageUrl string) http.Header {
+	from, ok := cache.GetReferer(pageUrl)
+	if !ok {
+		return header
+	}
+	to, err := url.Parse(pageUrl)
+	if err != nil {
+		return header
+	}
+	referer := refererFor(refererPolicyFor(to), from, to)
+	if referer == "" {
+		return header
+	}
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Referer", referer)
+	return header
+}
+
+// refererFor valor do Referer de uma navegação de from para to segundo a política
+func refererFor(policy, from string, to *url.URL) string {
+	source, err := url.Parse(from)
+	if err != nil || (source.Scheme != "http" && source.Scheme != "https") {
+		return ""
+	}
+	source.User, source.Fragment, source.RawFragment = nil, "", ""
+	full := source.String()
+	origin := source.Scheme + "://" + source.Host + "/"
+	sameOrigin := source.Scheme == to.Scheme && strings.EqualFold(source.Host, to.Host)
+	downgrade := source.Scheme == "https" && to.Scheme != "https"
+
+	switch policy {
+	case RefererUnsafeURL:
+		return full
+	case RefererOrigin:
+		return origin
+	case RefererSameOrigin:
+		if sameOrigin {
+			return full
+		}
+	case RefererStrictOriginWhenCrossOrigin:
+		if sameOrigin {
+			return full
+		}
+		if !downgrade {
+			return origin
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package crawler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+)
+
+func TestApplyHeadersUsesHostHeader(t *testing.T) {
+	setTestConfig(t)
+	config.Conf.Headers = &config.Headers{
+		Global: map[string]string{"Accept-Language": "pt-BR"},
+		Domains: []config.DomainHeaders{{
+			Domain:     "forum.i2p",
+			Headers:    map[string]string{"X-Forum": "1"},
+			UserAgents: []string{"ForumAgent/1.0"},
+		}},
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://ukeu3k5oycgaauneqgtnvselmt4yemvoilkln7jpvamvfx7dnkdq.b32.i2p/", nil)
+	req.Host = "forum.i2p"
+	applyHeaders(req)
+	if got := req.Header.Get("X-Forum"); got != "1" {
+		t.Errorf("X-Forum %q, want the forum.i2p header", got)
+	}
+	if got := req.Header.Get("User-Agent"); got != "ForumAgent/1.0" {
+		t.Errorf("User-Agent %q, want the forum.i2p pool", got)
+	}
+	if got := req.Header.Get("Accept-Language"); got != "pt-BR" {
+		t.Errorf("Accept-Language %q, want the global header", got)
+	}
+}
+
+func TestRefererOnlyForQueuedLinks(t *testing.T) {
+	setTestConfig(t)
+	setTestCache(t)
+	config.Conf.Headers.RefererPolicy = RefererUnsafeURL
+	referer := "http://referer.example/page"
+	queued, skipped := "http://referer.example/queued", "mailto:someone@referer.example"
+
+	handleAddToQueue([]string{queued, skipped}, 1, config.Seed{}, referer)
+	defer func() { _, _ = cache.GetFromQueueV2(1000) }()
+
+	if from, ok := cache.GetReferer(queued); !ok || from != referer {
+		t.Errorf("referer of queued link %q, %v", from, ok)
+	}
+	if from, ok := cache.GetReferer(skipped); ok {
+		t.Errorf("referer %q stored for a link outside the queue", from)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/gabrielmoura/WebCrawler/config"
 	"github.com/gabrielmoura/WebCrawler/infra/cache"
 	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"github.com/gabrielmoura/WebCrawler/infra/scope"
 	"go.uber.org/zap"
 	"net/http"
 	"net/url"
@@ -53,31 +54,38 @@ func isAllowedMIME(contentType string, allowedMIMEs []string) bool {
 	return false
 }
 
-// checkTLD checks if the link has an acceptable TLD
-func checkTLD(link string) bool {
-	if len(config.Conf.Filter.Tlds) > 0 {
-		linkUrl, err := url.Parse(link)
-		if err != nil {
-			return false
-		}
-		for _, tld := range config.Conf.Filter.Tlds {
-			if strings.HasSuffix(linkUrl.Hostname(), tld) {
-				return true
-			}
-		}
-		return false
-	}
-	return true
-}
-
-func handleAddToQueue(links []string, depth int) {
+// handleAddToQueue adiciona à fila os links dentro do escopo (FILTER.TLDS, regras de SCOPE e escopo da seed)
+// exceto links de saída de domínios autenticados, marcando-os com a seed da página de origem.
+// Um link já marcado mantém a sua seed, cuja prioridade é usada na fila.
+// Links de hosts que atingiram a cota de páginas são guardados fora da fila. A página referer é registrada
+// como origem apenas dos links enfileirados ou guardados.
+func handleAddToQueue(links []string, depth int, seed config.Seed, referer string) {
 	for _, link := range links {
-		if checkTLD(link) && isAllowedSchema(link, config.AcceptableSchema) {
-			err := cache.AddToQueue(link, depth)
+		if isAllowedSchema(link, config.AcceptableSchema) && scope.Allowed(link, depth) && scope.SameSeed(seed.Scope, link, seed.URL) {
+			linkUrl, err := url.Parse(link)
+			if err != nil || isLogoutLink(linkUrl) {
+				continue
+			}
+			if seed.Tag != "" {
+				if err := cache.SetSeed(link, seed.Tag); err != nil {
+					log.Logger.Error("error tagging link", zap.String("Link", link), zap.Error(err))
+				}
+			}
+			if defaultBudget.hostExhausted(linkUrl) {
+				holdForQuota(link, depth)
+				setReferer(link, referer)
+				continue
+			}
+			owner, ok := seedFor(link)
+			if !ok {
+				owner = seed
+			}
+			err = cache.AddToQueue(link, depth, owner.Priority)
 			if err != nil {
 				log.Logger.Error("error adding link to queue", zap.String("Link", link), zap.Error(err))
 				return
 			}
+			setReferer(link, referer)
 		}
 
 	}
@@ -113,55 +121,44 @@ func prepareLink(link string) (*url.URL, error) {
 	q.Del("#")
 	linkUrl.RawQuery = q.Encode()
 
+	// A URL é retornada junto com ErrDenySuffix para que recursos (css, js, imagens) possam ser registrados
 	if isDenyPostfix(linkUrl.Path, config.DenySuffixes) {
-		return nil, ErrDenySuffix
+		return linkUrl, ErrDenySuffix
 	}
 
 	return linkUrl, nil
 }
-func prepareParentLink(parentLink, link string) (*url.URL, error) {
 
-	// Remove o primeiro caractere se for uma barra ou ponto
-	if strings.HasPrefix(link, "/") || strings.HasPrefix(link, ".") {
-		link = link[1:]
+// prepareParentLink resolve um link relativo a partir da URL da página (RFC 3986), como o navegador,
+// e aplica os mesmos filtros de prepareLink (links locais, parâmetros utm e sufixos negados)
+func prepareParentLink(parentLink, link string) (*url.URL, error) {
+	link = strings.TrimSpace(link)
+	// Links vazios ou apenas com fragmento apontam para a própria página
+	if link == "" || strings.HasPrefix(link, "#") {
+		return nil, ErrEmptyPath
 	}
 
 	nURL, err := url.Parse(link)
 	if err != nil {
 		return nil, err
 	}
-	if nURL.Path == "" {
-		return nil, ErrEmptyPath
-	}
-
 	pURL, err := url.Parse(parentLink)
 	if err != nil {
 		return nil, err
 	}
 
-	nURL.Host = pURL.Host
-	nURL.Scheme = pURL.Scheme
-	log.Logger.Debug("New URL", zap.String("URL", nURL.String()))
-
-	return nURL, nil
+	resolved := pURL.ResolveReference(nURL)
+	log.Logger.Debug("New URL", zap.String("URL", resolved.String()))
+	return prepareLink(resolved.String())
 }
-func isStatusErr(status int, url *url.URL) bool {
+func isStatusErr(status int) bool {
 	if status == http.StatusOK {
 		return false
 	}
-
-	i2p := strings.HasSuffix(url.Host, ".i2p")
-	switch status {
-	case http.StatusConflict:
-		if i2p {
-			handleListHelperI2P(url.String())
-		}
-		return true
-	default:
-		return status < 200 || status >= 300
-	}
+	return status < 200 || status >= 300
 }
 
-func handleListHelperI2P(url string) {
-	// TODO: Lógica para adicionar a lista de helpers encontrados
+// hostMatches verifica se o host é o domínio ou um de seus subdomínios
+func hostMatches(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
 }
@@ -0,0 +1,35 @@
+package crawler
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPrepareParentLink(t *testing.T) {
+	setTestConfig(t)
+	const parent = "https://example.com/dir/page?x=1"
+	tests := []struct {
+		link string
+		want string
+		err  error
+	}{
+		{"../x", "https://example.com/x", nil},
+		{"img/a.png", "https://example.com/dir/img/a.png", ErrDenySuffix},
+		{"./other", "https://example.com/dir/other", nil},
+		{"/root", "https://example.com/root", nil},
+		{"//cdn.example.com/lib", "https://cdn.example.com/lib", nil},
+		{"?page=2", "https://example.com/dir/page?page=2", nil},
+		{"next?utm_source=feed&id=3", "https://example.com/dir/next?id=3", nil},
+		{"/file.pdf", "https://example.com/file.pdf", ErrDenySuffix},
+		{"#top", "", ErrEmptyPath},
+	}
+	for _, tt := range tests {
+		got, err := prepareParentLink(parent, tt.link)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: error %v, want %v", tt.link, err, tt.err)
+		}
+		if got != nil && got.String() != tt.want {
+			t.Errorf("%q resolved to %q, want %q", tt.link, got.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"github.com/gabrielmoura/go/pkg/ternary"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var ErrInvalidDestination = errors.New("invalid i2p destination")
+
+// i2pBase64 alfabeto base64 do I2P, com "-" e "~" no lugar de "+" e "/"
+var i2pBase64 = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-~").WithPadding(base64.NoPadding)
+
+// i2pMinDestinationSize tamanho mínimo de um destino I2P: chaves (384 bytes) e certificado (3 bytes)
+const i2pMinDestinationSize = 387
+
+// i2pAttempted hosts cuja resolução já foi tentada nesta execução
+var i2pAttempted sync.Map
+
+func isI2PHost(link *url.URL) bool {
+	return strings.HasSuffix(strings.ToLower(link.Hostname()), ".i2p")
+}
+
+func isB32Host(host string) bool {
+	return strings.HasSuffix(strings.ToLower(host), ".b32.i2p")
+}
+
+// i2pB32Address calcula o endereço .b32.i2p (sha256 do destino em base32) de um destino I2P em base64
+func i2pB32Address(destination string) (string, error) {
+	raw, err := i2pBase64.DecodeString(strings.TrimRight(strings.TrimSpace(destination), "="))
+	if err != nil || len(raw) < i2pMinDestinationSize {
+		return "", ErrInvalidDestination
+	}
+	sum := sha256.Sum256(raw)
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum[:])
+	return strings.ToLower(encoded) + ".b32.i2p", nil
+}
+
+// addressHelper extrai o destino do parâmetro i2paddresshelper de uma URL
+func addressHelper(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("i2paddresshelper")
+}
+
+// i2pHelperLinks extrai de uma página os destinos (links com i2paddresshelper) e os links de jump para o host
+func i2pHelperLinks(host string, body []byte) (destinations, jumpLinks []string) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, nil
+	}
+	var extract func(*html.Node)
+	extract = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, a := range n.Attr {
+				if a.Key != "href" {
+					continue
+				}
+				if dest := addressHelper(a.Val); dest != "" {
+					destinations = append(destinations, dest)
+				} else if strings.Contains(a.Val, host) && strings.Contains(strings.ToLower(a.Val), "jump") {
+					jumpLinks = append(jumpLinks, a.Val)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			extract(c)
+		}
+	}
+	extract(doc)
+	return destinations, jumpLinks
+}
+
+// destinationFromJump consulta um serviço de jump, procurando o destino nos redirecionamentos e no corpo
+func destinationFromJump(jumpLink, host string) string {
+	resp, err := httpRequest(jumpLink, nil)
+	if err != nil {
+		log.Logger.Debug("error querying jump service", zap.String("Jump", jumpLink), zap.Error(err))
+		return ""
+	}
+	defer resp.Body.Close()
+
+	// O serviço de jump redireciona para http://host/?i2paddresshelper=destino
+	for req := resp.Request; req != nil; {
+		if dest := addressHelper(req.URL.String()); dest != "" {
+			return dest
+		}
+		if req.Response == nil {
+			break
+		}
+		if dest := addressHelper(req.Response.Header.Get("Location")); dest != "" {
+			return dest
+		}
+		req = req.Response.Request
+	}
+	if dest := addressHelper(resp.Header.Get("Location")); dest != "" {
+		return dest
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return ""
+	}
+	destinations, _ := i2pHelperLinks(host, body)
+	if len(destinations) > 0 {
+		return destinations[0]
+	}
+	return ""
+}
+
+// handleListHelperI2P tenta resolver um host .i2p desconhecido pelo roteador (409):
+// usa os links de address helper/jump da página de erro e os serviços de jump configurados,
+// grava o endereço b32 aprendido no cache e devolve a URL à fila.
+func handleListHelperI2P(pageUrl string, depth int, body []byte) {
+	u, err := url.Parse(pageUrl)
+	if err != nil {
+		return
+	}
+	host := strings.ToLower(u.Hostname())
+	if isB32Host(host) {
+		return
+	}
+	if _, loaded := i2pAttempted.LoadOrStore(host, struct{}{}); loaded {
+		return
+	}
+	if _, ok := cache.GetI2PAddress(host); ok {
+		return
+	}
+
+	destinations, jumpLinks := i2pHelperLinks(host, body)
+	for _, service := range config.Conf.I2P.JumpServices {
+		jumpLinks = append(jumpLinks, strings.ReplaceAll(service, "%s", host))
+	}
+
+	var b32 string
+	for _, dest := range destinations {
+		if b32, err = i2pB32Address(dest); err == nil {
+			break
+		}
+	}
+	for _, jumpLink := range jumpLinks {
+		if b32 != "" {
+			break
+		}
+		if dest := destinationFromJump(jumpLink, host); dest != "" {
+			b32, _ = i2pB32Address(dest)
+		}
+	}
+	if b32 == "" {
+		log.Logger.Info("Could not resolve I2P host", zap.String("Host", host))
+		return
+	}
+
+	log.Logger.Info("I2P host resolved", zap.String("Host", host), zap.String("B32", b32))
+	if err := cache.SetI2PAddress(host, b32); err != nil {
+		log.Logger.Error("error saving I2P address", zap.String("Host", host), zap.Error(err))
+		return
+	}
+	if err := cache.AddToQueue(pageUrl, depth, queuePriority(pageUrl)); err != nil {
+		log.Logger.Error("error adding link to queue", zap.String("Link", pageUrl), zap.Error(err))
+	}
+}
+
+// i2pTransport troca, apenas na conexão, hosts .i2p resolvidos por serviços de jump pelo endereço b32.
+// Acima do transporte (cookie jar, autenticação, cabeçalhos e redirecionamentos) a requisição e a resposta
+// continuam com o nome do host.
+type i2pTransport struct {
+	base http.RoundTripper
+	// httpProxy o proxy HTTP recebe o host pela linha de requisição, que o net/http monta com o Host;
+	// nos demais casos o Host original é mantido
+	httpProxy bool
+}
+
+func (t *i2pTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b32 := resolvedI2PHost(req.URL)
+	if b32 == "" {
+		return t.base.RoundTrip(req)
+	}
+	resolved := req.Clone(req.Context())
+	resolved.Host = ternary.Ternary(t.httpProxy, "", req.URL.Host)
+	if port := req.URL.Port(); port != "" {
+		resolved.URL.Host = b32 + ":" + port
+	} else {
+		resolved.URL.Host = b32
+	}
+	resp, err := t.base.RoundTrip(resolved)
+	if resp != nil {
+		resp.Request = req
+	}
+	return resp, err
+}
+
+// resolvedI2PHost endereço b32 aprendido para o host .i2p da URL, vazio se não houver
+func resolvedI2PHost(link *url.URL) string {
+	host := strings.ToLower(link.Hostname())
+	if !strings.HasSuffix(host, ".i2p") || isB32Host(host) {
+		return ""
+	}
+	b32, _ := cache.GetI2PAddress(host)
+	return b32
+}
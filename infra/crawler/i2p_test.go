@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+)
+
+func TestI2PResolvedHostKeepsName(t *testing.T) {
+	setTestConfig(t)
+	setTestCache(t)
+	const b32 = "ukeu3k5oycgaauneqgtnvselmt4yemvoilkln7jpvamvfx7dnkdq.b32.i2p"
+	if err := cache.SetI2PAddress("forum.i2p", b32); err != nil {
+		t.Fatal(err)
+	}
+
+	cookieFile := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(cookieFile, []byte("forum.i2p\tFALSE\t/\tFALSE\t0\tsession\tabc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config.Conf.Cookies = &config.Cookies{Enabled: true, File: cookieFile}
+	jarsMu.Lock()
+	jars = make(map[string]*persistentJar)
+	jarsMu.Unlock()
+
+	// O proxy HTTP do I2P recebe a URL absoluta com o endereço b32
+	var urlHost, hostHeader, cookie string
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlHost, hostHeader, cookie = r.URL.Host, r.Host, r.Header.Get("Cookie")
+		http.SetCookie(w, &http.Cookie{Name: "seen", Value: "1", Path: "/"})
+		_, _ = fmt.Fprint(w, "<html></html>")
+	}))
+	defer proxySrv.Close()
+	config.Conf.Proxy.Routes = []config.ProxyRoute{{Suffix: "i2p", ProxyURL: proxySrv.URL}}
+
+	resp, err := httpRequest("http://forum.i2p/index", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if urlHost != b32 || hostHeader != b32 {
+		t.Errorf("proxy got URL host %q and Host %q, want %q", urlHost, hostHeader, b32)
+	}
+	if cookie != "session=abc" {
+		t.Errorf("Cookie %q, want the cookie imported for forum.i2p", cookie)
+	}
+	if got := resp.Request.URL.Host; got != "forum.i2p" {
+		t.Errorf("response request host %q, want forum.i2p", got)
+	}
+	stored, err := cache.LoadCookies("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, c := range stored {
+		if c.Cookie.Name == "seen" {
+			found = true
+			if c.URL != "http://forum.i2p/index" {
+				t.Errorf("cookie stored for %q, want forum.i2p", c.URL)
+			}
+		}
+	}
+	if !found {
+		t.Error("cookie set by the response was not stored")
+	}
+}
@@ -0,0 +1,36 @@
+package crawler
+
+import (
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"github.com/gabrielmoura/WebCrawler/infra/db"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+)
+
+// startJob registra o job atual como em execução, com o snapshot da configuração e as seeds carregadas
+func startJob(seeds []config.Seed) {
+	snapshot, err := config.Snapshot(seeds)
+	if err != nil {
+		log.Logger.Error("error serializing job config", zap.String("Job", config.Job), zap.Error(err))
+		return
+	}
+	if err := db.SaveJob(config.Job, data.JobRunning, snapshot); err != nil {
+		log.Logger.Error("error saving job", zap.String("Job", config.Job), zap.Error(err))
+	}
+}
+
+// stopJob marca o fim da execução do job atual: finished com a fila vazia ou exhausted quando o orçamento acabou
+func stopJob(status string) {
+	if err := db.SetJobStatus(config.Job, status); err != nil {
+		log.Logger.Error("error updating job", zap.String("Job", config.Job), zap.Error(err))
+		return
+	}
+	log.Logger.Info("Job stopped", zap.String("Job", config.Job), zap.String("Status", status))
+}
+
+// jobPaused verifica se o job atual foi pausado por outro processo
+func jobPaused() bool {
+	job, err := db.ReadJob(config.Job)
+	return err == nil && job.Status == data.JobPaused
+}
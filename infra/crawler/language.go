@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"github.com/gabrielmoura/WebCrawler/config"
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// extractHTMLLang retorna o idioma declarado em <html lang="..."> sem a região, ex: "pt-BR" -> "pt"
+func extractHTMLLang(n *html.Node) string {
+	if n.Type == html.ElementNode && n.Data == "html" {
+		for _, a := range n.Attr {
+			if a.Key == "lang" || a.Key == "xml:lang" {
+				lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(a.Val)), "-")
+				return lang
+			}
+		}
+		return ""
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if lang := extractHTMLLang(c); lang != "" {
+			return lang
+		}
+	}
+	return ""
+}
+
+// guessLanguage estima o idioma pelo número de stop words de cada idioma presentes no texto
+func guessLanguage(words []string) string {
+	var best string
+	var bestCount int
+	for lang, stopWords := range config.CommonStopWords {
+		set := make(map[string]struct{}, len(stopWords))
+		for _, w := range stopWords {
+			set[w] = struct{}{}
+		}
+		count := 0
+		for _, w := range words {
+			if _, ok := set[w]; ok {
+				count++
+			}
+		}
+		if count > bestCount || (count == bestCount && count > 0 && lang < best) {
+			best, bestCount = lang, count
+		}
+	}
+	return best
+}
+
+// detectLanguage detecta o idioma da página, priorizando o atributo lang do documento
+func detectLanguage(htmlDoc *html.Node, plainText []byte) string {
+	if lang := extractHTMLLang(htmlDoc); lang != "" {
+		return lang
+	}
+	return guessLanguage(splitWords(plainText))
+}
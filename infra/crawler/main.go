@@ -6,10 +6,16 @@ import (
 	"fmt"
 	"github.com/gabrielmoura/WebCrawler/config"
 	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
 	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"github.com/gabrielmoura/WebCrawler/infra/warc"
+	"github.com/gabrielmoura/go/pkg/ternary"
 	"go.uber.org/zap"
 	"golang.org/x/net/html"
-	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,14 +24,16 @@ var Wg sync.WaitGroup
 var (
 	mimeNotAllow        = errors.New("mime: not allowed")
 	ErrUnexpectedStatus = errors.New("unexpected status")
+	ErrNotModified      = errors.New("not modified")
 )
 
 // processPage processa uma página, extrai links e dados
 func processPage(pageUrl string, depth int) {
 
 	log.Logger.Debug(fmt.Sprintf("Looping queue, depth: %d", depth))
-	if depth > *config.MaxDepth {
-		log.Logger.Info(fmt.Sprintf("Reached max depth of %d, %d", *config.MaxDepth, depth))
+	seed, _ := seedFor(pageUrl)
+	if maxDepth := maxDepthFor(seed); depth > maxDepth {
+		log.Logger.Info(fmt.Sprintf("Reached max depth of %d, %d", maxDepth, depth))
 		return
 	}
 	// Só processa uma página se ela ainda não foi visitada
@@ -34,60 +42,173 @@ func processPage(pageUrl string, depth int) {
 		return
 	}
 
+	link, err := url.Parse(pageUrl)
+	if err != nil {
+		log.Logger.Debug("Invalid URL", zap.String("URL", pageUrl), zap.Error(err))
+		return
+	}
+	// Links de saída de domínios autenticados encerrariam a sessão
+	if isLogoutLink(link) {
+		log.Logger.Debug("Logout link skipped", zap.String("URL", pageUrl))
+		return
+	}
+	if err := defaultBudget.reserve(link); err != nil {
+		// Páginas de hosts na cota são guardadas; as demais voltam à fila
+		if errors.Is(err, ErrHostQuota) {
+			holdForQuota(pageUrl, depth)
+		} else if err := cache.AddToQueue(pageUrl, depth, seed.Priority); err != nil {
+			log.Logger.Error("error adding link to queue", zap.String("Link", pageUrl), zap.Error(err))
+		}
+		log.Logger.Debug("Budget", zap.String("URL", pageUrl), zap.Error(err))
+		return
+	}
+	// Só a página buscada é cobrada; adiamentos, erros, MIME recusado e páginas inalteradas devolvem a reserva
+	charged := false
+	defer func() { defaultBudget.settle(link, charged) }()
+
 	log.Logger.Info(fmt.Sprintf("Visiting %s", pageUrl))
-	plainText, htmlDoc, err := visitLink(pageUrl)
+	result, err := visitLink(pageUrl, depth)
+	if errors.Is(err, ErrSessionExpired) {
+		// Sessão expirada: novo login e uma nova tentativa
+		if err = relogin(pageUrl); err == nil {
+			result, err = visitLink(pageUrl, depth)
+		}
+	}
 	if err != nil {
+		var throttled *throttledError
+		if errors.As(err, &throttled) {
+			deferVisit(pageUrl, depth, throttled.until)
+			return
+		}
 		if errors.Is(err, mimeNotAllow) {
 			//log.Logger.Info(fmt.Sprintf("MIME not allowed: %s", pageUrl))
 			return
 		}
+		if errors.Is(err, ErrNotModified) {
+			handleUnchanged(pageUrl, depth, seed)
+			return
+		}
+		if errors.Is(err, ErrBodyTooLarge) || errors.Is(err, ErrUnsupportedEncoding) {
+			log.Logger.Info("Body not processed", zap.String("URL", pageUrl), zap.Error(err))
+			charged = true
+			SetVisited(pageUrl)
+			return
+		}
 		log.Logger.Debug(fmt.Sprintf("Error checking link: %s", err))
 		return
 	}
+	defer result.Release()
+	charged = true
 
-	links, err := extractLinks(pageUrl, htmlDoc)
+	// A página é gravada sob a URL final; se ela já foi visitada por outro link, só a cadeia é marcada
+	finalUrl := ternary.Ternary(result.FinalURL != "", result.FinalURL, pageUrl)
+	if finalUrl != pageUrl && GetVisited(finalUrl) {
+		setRedirectsVisited(result.Redirects)
+		aliasFetchState(pageUrl, finalUrl)
+		return
+	}
+
+	dataPage, links, err := buildPage(finalUrl, depth, result)
 	if err != nil {
-		log.Logger.Error(fmt.Sprintf("Error extracting links: %s", err))
+		log.Logger.Error(err.Error())
 		return
 	}
+	dataPage.Seed = seed.Tag
+
+	SetPage(finalUrl, dataPage)
+	SetLinks(finalUrl, links)
+	// O estado da busca e as revisitas ficam sob a URL final, com a URL pedida como apelido
+	saveFetchState(finalUrl, depth, dataPage)
+	aliasFetchState(pageUrl, finalUrl)
+
+	setRedirectsVisited(result.Redirects)
+	SetVisited(finalUrl)
+
+	handleAddToQueue(dataPage.Links, depth+1, seed, finalUrl)
+	if config.Conf.Filter.EnqueueHidden {
+		handleAddToQueue(dataPage.HiddenServices, depth+1, seed, finalUrl)
+	}
+}
+
+// buildPage executa a extração (links, dados, palavras e idioma) sobre o conteúdo buscado
+func buildPage(pageUrl string, depth int, result *fetchResult) (*data.Page, []data.Link, error) {
+	plainText, htmlDoc := result.Body, result.HTML
+
+	links, err := extractLinks(pageUrl, htmlDoc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error extracting links: %w", err)
+	}
 
 	dataPage, err := extractData(htmlDoc)
 	if err != nil {
-		log.Logger.Error(fmt.Sprintf("Error extracting data: %s", err))
-		return
+		return nil, nil, fmt.Errorf("error extracting data: %w", err)
 	}
-	words, _ := countWordsInText(plainText)
+	// O texto visível é extraído uma vez e usado na contagem de palavras, no conteúdo e na detecção do idioma
+	visibleText := extractPlainText(plainText)
+	words, _ := countWordsInText(visibleText)
 
 	dataPage.Words = words
+	dataPage.Content = strings.Join(strings.Fields(string(visibleText)), " ")
+	dataPage.Language = detectLanguage(htmlDoc, visibleText)
+	dataPage.HiddenServices = extractHiddenServices(htmlDoc, visibleText)
 	dataPage.Url = pageUrl
-	dataPage.Links = links
+	dataPage.Links = linkTargets(links)
+	dataPage.Depth = depth
+	dataPage.WarcRecordID = result.WarcRecordID
+	dataPage.Redirects = result.Redirects
+	dataPage.ETag = result.ETag
+	dataPage.LastModified = result.LastModified
+	dataPage.ContentHash = result.ContentHash
+	dataPage.Truncated = result.Truncated
+	dataPage.ContentEncoding = result.ContentEncoding
+	dataPage.CompressedSize = result.CompressedSize
+	dataPage.UncompressedSize = int64(len(result.Body))
 	dataPage.Timestamp = time.Now()
+	dataPage.LastChecked = dataPage.Timestamp
 	dataPage.Visited = true
+	dataPage.Job = config.Job
 
-	SetPage(pageUrl, dataPage)
-
-	SetVisited(pageUrl)
-
-	handleAddToQueue(links, depth+1)
+	return dataPage, links, nil
 }
-func HandleQueue(initialURL string) {
-	// Só processa a fila se ela não estiver vazia
-	log.Logger.Info("Handling queue")
-	ok, _, err := cache.GetFromQueue()
 
-	if err != nil { // Check if queue is empty
-		log.Logger.Info("Queue is empty", zap.Error(err))
+// HandleQueue adiciona à fila as seeds ainda não visitadas e processa a fila, retomando a execução anterior
+func HandleQueue(seedList []config.Seed) {
+	log.Logger.Info("Handling queue", zap.String("Job", config.Job), zap.Int("Seeds", len(seedList)))
+	startJob(seedList)
+	defaultBudget.start()
+	requeueHeld()
+	for _, seed := range seedList {
+		addSeed(seed)
 	}
-	if ok == "" {
-		cache.AddToQueue(initialURL, 0)
+	if throttleEnabled() {
+		go throttleStatsLoop(time.Minute)
 	}
 	loopQueue()
 }
 
 func loopQueue() {
 	for {
+		// Um job pausado (job pause) para após o lote atual, mantendo a fila para ser retomado
+		if jobPaused() {
+			log.Logger.Info("Job paused", zap.String("Job", config.Job))
+			return
+		}
+		// Com o orçamento esgotado o crawler para sem retirar mais páginas da fila
+		if reason := defaultBudget.exhausted(); reason != "" {
+			log.Logger.Info("Crawl budget exhausted", zap.String("Job", config.Job), zap.String("Budget", reason))
+			stopJob(data.JobExhausted)
+			return
+		}
+		if config.Conf.Recrawl.Enabled || pendingDeferrals() {
+			enqueueDueVisits()
+		}
 		links, _ := cache.GetFromQueueV2(*config.MaxConcurrency) // Get a batch of links
 		if len(links) == 0 {
+			// Com revisitas agendadas ou páginas adiadas pelo controle de taxa o crawler aguarda as próximas vencidas
+			if config.Conf.Recrawl.Enabled || pendingDeferrals() {
+				waitDueVisits()
+				continue
+			}
 			break
 		}
 
@@ -104,37 +225,177 @@ func loopQueue() {
 		}
 		Wg.Wait()
 	}
+	stopJob(data.JobFinished)
+}
+
+// fetchResult resultado da busca de uma página
+type fetchResult struct {
+	Body         []byte
+	HTML         *html.Node
+	WarcRecordID string
+	// FinalURL URL após os redirecionamentos, vazia se não houve redirecionamento
+	FinalURL  string
+	Redirects []data.Redirect
+	// Validadores da resposta para requisições condicionais
+	ETag         string
+	LastModified string
+	ContentHash  string
+	// Truncated o corpo excedeu o limite do tipo de conteúdo e foi cortado
+	Truncated bool
+	// ContentEncoding codificação original (gzip, deflate, br, zstd) e tamanho comprimido recebido
+	ContentEncoding string
+	CompressedSize  int64
+	// release libera a memória reservada para o corpo no orçamento global
+	release func()
+}
+
+// Release libera a reserva de memória do corpo, se houver
+func (r *fetchResult) Release() {
+	if r.release != nil {
+		r.release()
+	}
 }
 
-func visitLink(pageUrl string) ([]byte, *html.Node, error) {
-	resp, err := httpRequest(pageUrl)
+func visitLink(pageUrl string, depth int) (*fetchResult, error) {
+	if err := ensureLogin(pageUrl); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	// Em revisitas, envia os validadores da última busca (If-None-Match/If-Modified-Since),
+	// gravados sob a URL final quando a página foi redirecionada
+	stateUrl := fetchStateURL(pageUrl)
+	state, revisit := cache.GetFetchState(stateUrl)
+	var header http.Header
+	if revisit {
+		header = conditionalHeaders(state)
+	}
+	header = withReferer(header, pageUrl)
+	if link, err := url.Parse(pageUrl); err == nil {
+		if err := defaultThrottler.wait(link); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := httpRequest(pageUrl, header)
+	// 429/503 e timeouts desaceleram o host e adiam a página
+	retryErr := recordFetch(pageUrl, resp, err)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error fetching URL %s: %w", pageUrl, err)
+		if retryErr != nil {
+			return nil, retryErr
+		}
+		// Em ciclos ou excesso de redirecionamentos a cadeia é marcada como visitada para não ser buscada de novo
+		if resp != nil && (errors.Is(err, ErrRedirectLoop) || errors.Is(err, ErrTooManyRedirects)) {
+			log.Logger.Info("Redirect error", zap.String("URL", pageUrl), zap.Error(err))
+			setRedirectsVisited(redirectChain(resp))
+			SetVisited(requestURL(resp.Request))
+		}
+		return nil, fmt.Errorf("error fetching URL %s: %w", pageUrl, err)
 	}
 	defer resp.Body.Close()
 
-	if isStatusErr(resp.StatusCode, resp.Request.URL) {
+	if resp.StatusCode == http.StatusNotModified && revisit {
+		markUnchanged(stateUrl, depth, state, resp.Header)
+		return nil, ErrNotModified
+	}
+
+	if isStatusErr(resp.StatusCode) {
 		// TODO: Implementar lógica para por em outra fila e ternar novamente
 		log.Logger.Info("Status Error", zap.String("URL", pageUrl), zap.String("Status", resp.Status))
-		return nil, nil, ErrUnexpectedStatus
+		// Lido como os demais corpos: limite do tipo de conteúdo (<= 0 sem limite) e orçamento de memória
+		var bodyBytes []byte
+		if body, err := readBody(resp); err == nil {
+			defer body.release()
+			bodyBytes = body.Data
+			defaultBudget.addBytes(body.CompressedSize)
+			archiveResponse(resp, body, start, depth)
+		}
+		// O roteador I2P responde 409 para hosts que não estão no seu addressbook
+		if resp.StatusCode == http.StatusConflict && isI2PHost(resp.Request.URL) {
+			handleListHelperI2P(pageUrl, depth, bodyBytes)
+		}
+		if retryErr != nil {
+			return nil, retryErr
+		}
+		return nil, ErrUnexpectedStatus
 	}
 
 	// Streamlined MIME type check and early return
 	if !isAllowedMIME(resp.Header.Get("Content-Type"), config.AcceptableMimeTypes) {
-		return nil, nil, mimeNotAllow
+		return nil, mimeNotAllow
+	}
+
+	// Read the (decoded) response body up to the content type limit
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	defaultBudget.addBytes(body.CompressedSize)
+
+	// A página de login exibida com a sessão expirada não é arquivada
+	if isLoggedOut(pageUrl, resp, body.Data) {
+		body.release()
+		return nil, ErrSessionExpired
+	}
+	warcRecordID := archiveResponse(resp, body, start, depth)
+
+	// Codificação desconhecida: o corpo foi arquivado como recebido, mas não pode ser interpretado
+	if encoding := undecodedEncoding(resp); encoding != "" {
+		body.release()
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
 	}
 
-	// Efficiently read the response body into a buffer
-	bodyBytes, err := io.ReadAll(resp.Body)
+	// Corpo idêntico ao da última busca dispensa nova extração
+	if revisit && state.ContentHash == contentHash(body.Data) {
+		body.release()
+		markUnchanged(stateUrl, depth, state, resp.Header)
+		return nil, ErrNotModified
+	}
+
+	result, err := parseBody(body.Data)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error reading response body: %w", err)
+		body.release()
+		return nil, err
+	}
+	result.release = body.release
+	result.Truncated = body.Truncated
+	result.ContentEncoding = body.Encoding
+	result.CompressedSize = body.CompressedSize
+	result.WarcRecordID = warcRecordID
+	result.setValidators(resp.Header, body.Data)
+	if result.Redirects = redirectChain(resp); len(result.Redirects) > 0 {
+		result.FinalURL = requestURL(resp.Request)
 	}
+	return result, nil
+}
 
+// parseBody interpreta o corpo da resposta como HTML
+func parseBody(body []byte) (*fetchResult, error) {
 	// Parse HTML from the buffered content
-	htmlDoc, err := html.Parse(bytes.NewReader(bodyBytes))
+	htmlDoc, err := html.Parse(bytes.NewReader(body))
 	if err != nil {
-		return nil, nil, fmt.Errorf("error parsing HTML: %w", err)
+		return nil, fmt.Errorf("error parsing HTML: %w", err)
 	}
+	return &fetchResult{Body: body, HTML: htmlDoc}, nil
+}
 
-	return bodyBytes, htmlDoc, nil
+// archiveResponse grava a busca no WARC, se habilitado, e retorna o WARC-Record-ID do response.
+// Corpos comprimidos são gravados como recebidos, com Content-Encoding e Content-Length originais.
+func archiveResponse(resp *http.Response, body *responseBody, start time.Time, depth int) string {
+	if !warc.Enabled() {
+		return ""
+	}
+	payload := body.Data
+	if decoded, ok := resp.Body.(*decodedBody); ok && body.Received != nil {
+		received := *resp
+		received.Header = decoded.receivedHeader(resp.Header)
+		resp, payload = &received, body.Received
+	}
+	recordID, err := warc.Archive(resp, payload, ternary.Ternary(body.Truncated, warc.TruncatedLength, ""), map[string]string{
+		"fetchTimeMs": strconv.FormatInt(time.Since(start).Milliseconds(), 10),
+		"depth":       strconv.Itoa(depth),
+	})
+	if err != nil {
+		log.Logger.Error("error writing WARC", zap.String("URL", resp.Request.URL.String()), zap.Error(err))
+		return ""
+	}
+	return recordID
 }
@@ -0,0 +1,57 @@
+package crawler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+)
+
+// setTestConfig define uma configuração mínima para os testes do pacote, sem proxy, cookies ou controle de taxa
+func setTestConfig(tb testing.TB) {
+	tb.Helper()
+	if log.Logger == nil {
+		log.Logger = zap.NewNop()
+	}
+	config.Conf = &config.Config{
+		Job:       config.DefaultJob,
+		UserAgent: "WebCrawler-test",
+		Headers:   &config.Headers{},
+		Cookies:   &config.Cookies{},
+		Throttle:  &config.Throttle{},
+		Budget:    &config.Budget{},
+		Filter:    &config.Filter{},
+		Proxy: &config.Proxy{
+			Strategy: strategyRoundRobin,
+			Pool:     &config.ProxyPool{MaxFailures: 3, BanWindow: time.Minute},
+		},
+		HTTP: &config.HTTP{
+			Timeout:             5 * time.Second,
+			ProxyTimeout:        5 * time.Second,
+			DialTimeout:         time.Second,
+			IdleConnTimeout:     90 * time.Second,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 10,
+			MaxRedirects:        10,
+			MaxBodySize:         10 << 20,
+		},
+	}
+	defaultFetcher = &fetcher{pools: make(map[string]*proxyPool)}
+}
+
+var testCacheOnce sync.Once
+
+// setTestCache abre o cache em memória, compartilhado pelos testes do pacote
+func setTestCache(tb testing.TB) {
+	tb.Helper()
+	testCacheOnce.Do(func() {
+		config.Conf.Cache = &config.CacheConfig{Mode: "mem"}
+		if err := cache.InitCache(); err != nil {
+			tb.Fatal(err)
+		}
+	})
+}
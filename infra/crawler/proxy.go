@@ -1,43 +1,291 @@
 package crawler
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+	"golang.org/x/net/proxy"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
-func proxyClient() *http.Client {
-	urlProxy, _ := url.Parse(config.Conf.Proxy.ProxyURL)
+// proxyRoute rota de acesso escolhida para uma URL
+type proxyRoute struct {
+	// name sufixo da rota, "" para a rota padrão
+	name string
+	// proxyURLs proxies do pool da rota
+	proxyURLs []*url.URL
+	// direct acesso sem proxy, apenas quando configurado explicitamente (PROXY desabilitado ou "direct");
+	// uma rota de proxy sem proxies válidos recusa as conexões
+	direct   bool
+	strategy string
+	timeout  time.Duration
+	// isolate usa credenciais SOCKS por host (isolamento de circuitos no Tor)
+	isolate bool
+}
+
+// parseProxyURLs interpreta o proxy principal e os adicionais, ignorando vazios, "direct" e inválidos
+func parseProxyURLs(first string, others []string) []*url.URL {
+	var urls []*url.URL
+	for _, raw := range append([]string{first}, others...) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" || raw == "direct" {
+			continue
+		}
+		urlProxy, err := url.Parse(raw)
+		if err == nil && (urlProxy.Host == "" || (!isSocks(urlProxy) && urlProxy.Scheme != "http" && urlProxy.Scheme != "https")) {
+			err = config.ErrInvalidProxy
+		}
+		if err != nil {
+			log.Logger.Error("invalid proxy URL", zap.String("Proxy", raw), zap.Error(err))
+			continue
+		}
+		urls = append(urls, urlProxy)
+	}
+	return urls
+}
+
+// defaultRoute rota usada quando nenhum sufixo corresponde ao host
+func defaultRoute() proxyRoute {
+	if config.Conf.Proxy.Enabled {
+		return proxyRoute{
+			proxyURLs: parseProxyURLs(config.Conf.Proxy.ProxyURL, config.Conf.Proxy.ProxyURLs),
+			strategy:  config.Conf.Proxy.Strategy,
+			timeout:   config.Conf.HTTP.ProxyTimeout,
+			isolate:   config.Conf.Proxy.Isolate,
+		}
+	}
+	return proxyRoute{timeout: config.Conf.HTTP.Timeout, direct: true}
+}
+
+// routeTable rotas resolvidas a partir da configuração, uma vez por fetcher
+type routeTable struct {
+	def proxyRoute
+	// routes rotas por sufixo, na ordem da configuração
+	routes []proxyRoute
+}
+
+// newRouteTable interpreta a rota padrão e as rotas por sufixo (PROXY.ROUTES)
+func newRouteTable() *routeTable {
+	table := &routeTable{def: defaultRoute()}
+	for _, r := range config.Conf.Proxy.Routes {
+		suffix := strings.TrimPrefix(strings.ToLower(r.Suffix), ".")
+		route := proxyRoute{
+			name:      suffix,
+			proxyURLs: parseProxyURLs(r.ProxyURL, r.ProxyURLs),
+			strategy:  r.Strategy,
+			isolate:   r.Isolate,
+			direct:    strings.TrimSpace(r.ProxyURL) == "direct" && len(r.ProxyURLs) == 0,
+		}
+		if route.strategy == "" {
+			route.strategy = config.Conf.Proxy.Strategy
+		}
+		if r.Timeout > 0 {
+			route.timeout = r.Timeout
+		} else if len(route.proxyURLs) > 0 {
+			route.timeout = config.Conf.HTTP.ProxyTimeout
+		} else {
+			route.timeout = config.Conf.HTTP.Timeout
+		}
+		table.routes = append(table.routes, route)
+	}
+	return table
+}
+
+// lookup rota do sufixo mais longo que corresponde ao host, ou a padrão
+func (t *routeTable) lookup(host string) proxyRoute {
+	route, matched := t.def, -1
+	for _, r := range t.routes {
+		if len(r.name) <= matched || (host != r.name && !strings.HasSuffix(host, "."+r.name)) {
+			continue
+		}
+		route, matched = r, len(r.name)
+	}
+	return route
+}
+
+// routeFor escolhe a rota de proxy pelo sufixo mais longo que corresponde ao host
+func routeFor(link *url.URL) proxyRoute {
+	return defaultFetcher.routeTable().lookup(strings.ToLower(link.Hostname()))
+}
+
+// isSocks verifica se a URL do proxy é SOCKS5
+func isSocks(proxyURL *url.URL) bool {
+	return proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h"
+}
+
+// socksIsolationNonce senha usada no isolamento por host, nova a cada execução para renovar os circuitos
+var socksIsolationNonce = func() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}()
+
+// socksAuth credenciais SOCKS para a conexão com addr (host:porta)
+func socksAuth(route proxyRoute, proxyURL *url.URL, addr string) *proxy.Auth {
+	if route.isolate {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		return &proxy.Auth{User: host, Password: socksIsolationNonce}
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		return &proxy.Auth{User: user.Username(), Password: password}
+	}
+	return nil
+}
+
+// socksDialContext conecta pelo proxy SOCKS5 enviando o nome do host ao proxy,
+// de forma que nomes .onion/.i2p nunca passem pelo resolvedor local.
+func socksDialContext(route proxyRoute, proxyURL *url.URL, forward *proxyDialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, socksAuth(route, proxyURL, addr), forward)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.(proxy.ContextDialer).DialContext(ctx, network, addr)
+	}
+}
+
+// newTransport cria o transporte para um proxy da rota (HTTP(S) ou SOCKS5), ou acesso direto se proxyURL for nil
+func newTransport(route proxyRoute, proxyURL *url.URL) *http.Transport {
+	cfg := config.Conf.HTTP
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}
 	transport := &http.Transport{
-		Proxy: http.ProxyURL(urlProxy),
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		// Com DialContext ou Proxy definidos o HTTP/2 só é negociado se forçado
+		ForceAttemptHTTP2: cfg.HTTP2,
+		// A decodificação (gzip, deflate, br, zstd) é feita pelo decodingTransport
+		DisableCompression: true,
+	}
+	if proxyURL != nil {
+		// Falhas ao conectar no próprio proxy são marcadas para o pool
+		proxyDialer := &proxyDialer{dialer: dialer}
+		if isSocks(proxyURL) {
+			transport.DialContext = socksDialContext(route, proxyURL, proxyDialer)
+		} else {
+			transport.DialContext = proxyDialer.DialContext
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
 	}
+	return transport
+}
+
+// fetcher mantém um pool de proxies por rota, cada proxy com seu cliente HTTP,
+// reaproveitando conexões (keep-alive) e sessões TLS
+type fetcher struct {
+	mu    sync.Mutex
+	pools map[string]*proxyPool
+
+	routesOnce sync.Once
+	routes     *routeTable
+}
+
+var defaultFetcher = &fetcher{pools: make(map[string]*proxyPool)}
+
+// routeTable rotas da configuração, resolvidas no primeiro uso
+func (f *fetcher) routeTable() *routeTable {
+	f.routesOnce.Do(func() { f.routes = newRouteTable() })
+	return f.routes
+}
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   30 * time.Second, // Definir um timeout de 5 segundos
+// pool retorna o pool da rota, criando-o no primeiro uso
+func (f *fetcher) pool(route proxyRoute) *proxyPool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pool, ok := f.pools[route.name]
+	if !ok {
+		pool = newProxyPool(route)
+		f.pools[route.name] = pool
 	}
+	return pool
+}
 
-	return client
+// httpRequest busca a URL pela rota de proxy correspondente, com cabeçalhos adicionais opcionais
+func httpRequest(pageUrl string, header http.Header) (resp *http.Response, err error) {
+	req, err := http.NewRequest("GET", pageUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return doRequest(req)
 }
-func httpClient() *http.Client {
-	if config.Conf.Proxy.Enabled {
-		return proxyClient()
-	} else {
-		return &http.Client{
-			Timeout: 5 * time.Second, // Definir um timeout de 5 segundos
-			Transport: &http.Transport{
-				MaxIdleConns:        10,
-				MaxIdleConnsPerHost: 10,
-			},
+
+// doRequest envia a requisição pela rota de proxy do host, com os cabeçalhos e a autenticação do domínio.
+// Cada salto de redirecionamento usa a rota do seu host: o cliente para no salto que muda de rota
+// (checkRedirect) e ele é enviado de novo pela rota seguinte, sem vazar nomes .onion/.i2p para o acesso direto.
+func doRequest(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for {
+		route := routeFor(req.URL)
+		log.Logger.Debug("Proxy route", zap.String("URL", req.URL.String()), zap.String("Route", route.name))
+		applyHeaders(req)
+		applyAuth(req)
+		resp, err := defaultFetcher.pool(route).do(req)
+		if err != nil {
+			return resp, err
+		}
+		next, err := nextRouteHop(ctx, resp)
+		if next == nil || err != nil {
+			return resp, err
 		}
+		req = next
 	}
 }
 
-func httpRequest(pageUrl string) (resp *http.Response, err error) {
-	client := httpClient()
-	req, _ := http.NewRequest("GET", pageUrl, nil)
-	req.Header.Set("User-Agent", config.Conf.UserAgent)
-	resp, err = client.Do(req)
-	return
+// nextRouteHop próximo salto de um redirecionamento interrompido pela troca de rota, nil se a resposta é final
+func nextRouteHop(ctx context.Context, resp *http.Response) (*http.Request, error) {
+	if !isRedirectStatus(resp.StatusCode) || resp.Header.Get("Location") == "" {
+		return nil, nil
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return nil, nil
+	}
+	next, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+	if err != nil {
+		return nil, nil
+	}
+	// Response liga o salto ao anterior, como faz o cliente, para redirectChain
+	next.Response = resp
+	if referer := resp.Request.Header.Get("Referer"); referer != "" {
+		next.Header.Set("Referer", referer)
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 2<<10))
+	_ = resp.Body.Close()
+	if err := redirectLimits(next, redirectVia(resp)); err != nil {
+		return nil, &url.Error{Op: "Get", URL: location.String(), Err: err}
+	}
+	return next, nil
+}
+
+// redirectVia requisições já feitas na cadeia de redirecionamentos da resposta, da primeira à última
+func redirectVia(resp *http.Response) []*http.Request {
+	var via []*http.Request
+	for req := resp.Request; req != nil; {
+		via = append([]*http.Request{req}, via...)
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+	return via
 }
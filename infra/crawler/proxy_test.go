@@ -0,0 +1,367 @@
+package crawler
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gabrielmoura/WebCrawler/config"
+)
+
+// socksRequest pedido CONNECT recebido pelo servidor SOCKS5 de teste
+type socksRequest struct {
+	user, password string
+	addrType       byte
+	host           string
+	port           int
+}
+
+// startSocksServer inicia um servidor SOCKS5 mínimo que registra os pedidos CONNECT e responde sucesso
+func startSocksServer(t *testing.T) (*url.URL, <-chan socksRequest) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	requests := make(chan socksRequest, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if req, err := serveSocks(conn); err == nil {
+					requests <- req
+				}
+			}()
+		}
+	}()
+	return &url.URL{Scheme: "socks5h", Host: ln.Addr().String()}, requests
+}
+
+func serveSocks(conn net.Conn) (socksRequest, error) {
+	var req socksRequest
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return req, err
+	}
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return req, err
+	}
+	method := byte(0x00)
+	for _, m := range methods {
+		if m == 0x02 {
+			method = 0x02
+		}
+	}
+	if _, err := conn.Write([]byte{0x05, method}); err != nil {
+		return req, err
+	}
+	if method == 0x02 {
+		var err error
+		if req.user, req.password, err = readSocksAuth(conn); err != nil {
+			return req, err
+		}
+		if _, err := conn.Write([]byte{0x01, 0x00}); err != nil {
+			return req, err
+		}
+	}
+
+	// VER CMD RSV ATYP
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return req, err
+	}
+	req.addrType = header[3]
+	switch req.addrType {
+	case 0x01:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return req, err
+		}
+		req.host = net.IP(ip).String()
+	case 0x04:
+		ip := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return req, err
+		}
+		req.host = net.IP(ip).String()
+	case 0x03:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return req, err
+		}
+		host := make([]byte, size[0])
+		if _, err := io.ReadFull(conn, host); err != nil {
+			return req, err
+		}
+		req.host = string(host)
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return req, err
+	}
+	req.port = int(binary.BigEndian.Uint16(port))
+	_, err := conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	return req, err
+}
+
+func readSocksAuth(conn net.Conn) (string, string, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return "", "", err
+	}
+	user := make([]byte, head[1])
+	if _, err := io.ReadFull(conn, user); err != nil {
+		return "", "", err
+	}
+	size := make([]byte, 1)
+	if _, err := io.ReadFull(conn, size); err != nil {
+		return "", "", err
+	}
+	password := make([]byte, size[0])
+	if _, err := io.ReadFull(conn, password); err != nil {
+		return "", "", err
+	}
+	return string(user), string(password), nil
+}
+
+func dialSocks(t *testing.T, route proxyRoute, proxyURL *url.URL, requests <-chan socksRequest, addr string) socksRequest {
+	t.Helper()
+	dial := socksDialContext(route, proxyURL, &proxyDialer{dialer: &net.Dialer{Timeout: time.Second}})
+	conn, err := dial(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("dial %s: %v", addr, err)
+	}
+	_ = conn.Close()
+	select {
+	case req := <-requests:
+		return req
+	case <-time.After(time.Second):
+		t.Fatalf("no CONNECT received for %s", addr)
+	}
+	return socksRequest{}
+}
+
+func TestSocksDialRemoteDNS(t *testing.T) {
+	proxyURL, requests := startSocksServer(t)
+	for _, host := range []string{
+		"expyuzz4wqqyqhjn.onion",
+		"2gzyxa5ihm7nsggfxnu52rck2vv4rvmdlkiu3zzui5du4xyclen53wid.onion",
+		"stats.i2p",
+		"example.com",
+	} {
+		req := dialSocks(t, proxyRoute{}, proxyURL, requests, net.JoinHostPort(host, "80"))
+		if req.addrType != 0x03 {
+			t.Errorf("%s: address type %#x, want domain name (0x03)", host, req.addrType)
+		}
+		if req.host != host || req.port != 80 {
+			t.Errorf("CONNECT %s:%d, want %s:80", req.host, req.port, host)
+		}
+		if req.user != "" {
+			t.Errorf("%s: unexpected credentials %q without isolation", host, req.user)
+		}
+	}
+}
+
+func TestSocksDialProxyCredentials(t *testing.T) {
+	proxyURL, requests := startSocksServer(t)
+	proxyURL.User = url.UserPassword("alice", "secret")
+	req := dialSocks(t, proxyRoute{}, proxyURL, requests, "example.onion:80")
+	if req.user != "alice" || req.password != "secret" {
+		t.Errorf("credentials %q/%q, want alice/secret", req.user, req.password)
+	}
+}
+
+func TestSocksDialIsolation(t *testing.T) {
+	proxyURL, requests := startSocksServer(t)
+	route := proxyRoute{isolate: true}
+
+	credentials := make(map[string]string)
+	for i, host := range []string{"a.onion", "b.onion", "a.onion", "c.i2p"} {
+		req := dialSocks(t, route, proxyURL, requests, net.JoinHostPort(host, strconv.Itoa(80+i)))
+		if req.user == "" || req.password == "" {
+			t.Fatalf("%s: isolation sent no credentials", host)
+		}
+		if req.user != host {
+			t.Errorf("%s: username %q, want the host", host, req.user)
+		}
+		pair := req.user + ":" + req.password
+		if previous, ok := credentials[host]; ok && previous != pair {
+			t.Errorf("%s: credentials changed between connections: %q != %q", host, previous, pair)
+		}
+		for other, otherPair := range credentials {
+			if other != host && otherPair == pair {
+				t.Errorf("%s and %s share credentials %q", host, other, pair)
+			}
+		}
+		credentials[host] = pair
+	}
+}
+
+// countingServer servidor de teste que conta as conexões TCP abertas pelos clientes
+func countingServer(b *testing.B, handler http.Handler) (*httptest.Server, *atomic.Int64) {
+	b.Helper()
+	conns := new(atomic.Int64)
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	b.Cleanup(srv.Close)
+	return srv, conns
+}
+
+// benchmarkRoutes prepara um servidor acessado diretamente e um proxy HTTP de teste para a rota onion,
+// retornando uma URL de cada rota e o contador de conexões dos dois servidores
+func benchmarkRoutes(b *testing.B) ([]string, func() int64) {
+	setTestConfig(b)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "<html><body>ok</body></html>")
+	})
+	origin, originConns := countingServer(b, ok)
+	// Para um proxy HTTP a requisição chega com a URL absoluta; o servidor responde no lugar do destino
+	proxySrv, proxyConns := countingServer(b, ok)
+	config.Conf.Proxy.Routes = []config.ProxyRoute{{Suffix: "onion", ProxyURL: proxySrv.URL}}
+
+	urls := []string{origin.URL + "/page", "http://example.onion/page"}
+	return urls, func() int64 { return originConns.Load() + proxyConns.Load() }
+}
+
+func reportConns(b *testing.B, conns func() int64) {
+	b.ReportMetric(float64(conns())/float64(b.N), "conns/op")
+}
+
+// BenchmarkFetcherPerRoute requisições pelo fetcher, que mantém um transporte por rota e reaproveita as conexões
+func BenchmarkFetcherPerRoute(b *testing.B) {
+	urls, conns := benchmarkRoutes(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := httpRequest(urls[i%len(urls)], nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	reportConns(b, conns)
+}
+
+// BenchmarkTransportPerRequest abordagem anterior: um transporte novo para cada requisição
+func BenchmarkTransportPerRequest(b *testing.B) {
+	urls, conns := benchmarkRoutes(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req, err := http.NewRequest(http.MethodGet, urls[i%len(urls)], nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		route := routeFor(req.URL)
+		var proxyURL *url.URL
+		if len(route.proxyURLs) > 0 {
+			proxyURL = route.proxyURLs[0]
+		}
+		transport := newTransport(route, proxyURL)
+		client := &http.Client{Transport: transport, Timeout: route.timeout}
+		resp, err := client.Do(req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		transport.CloseIdleConnections()
+	}
+	reportConns(b, conns)
+}
+
+func TestProxyRouteWithoutProxyRefuses(t *testing.T) {
+	setTestConfig(t)
+	config.Conf.Proxy.Routes = []config.ProxyRoute{{Suffix: "onion", ProxyURL: "socks5h//localhost:9050"}}
+	resp, err := httpRequest("http://example.onion/", nil)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrNoProxyAvailable) {
+		t.Fatalf("error %v, want %v", err, ErrNoProxyAvailable)
+	}
+}
+
+func TestRedirectSwitchesRoute(t *testing.T) {
+	setTestConfig(t)
+	var origin *httptest.Server
+	var proxied, direct atomic.Int32
+	// Proxy HTTP de teste da rota onion: a requisição chega com a URL absoluta do host .onion
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		if r.URL.Hostname() != "hidden.onion" {
+			t.Errorf("proxy received %s, want only the .onion host", r.URL)
+		}
+		http.Redirect(w, r, origin.URL+"/end", http.StatusFound)
+	}))
+	defer proxySrv.Close()
+	origin = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		direct.Add(1)
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "http://hidden.onion/page", http.StatusMovedPermanently)
+		default:
+			_, _ = io.WriteString(w, "<html><body>end</body></html>")
+		}
+	}))
+	defer origin.Close()
+	config.Conf.Proxy.Routes = []config.ProxyRoute{{Suffix: "onion", ProxyURL: proxySrv.URL}}
+
+	resp, err := httpRequest(origin.URL+"/start", nil)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || requestURL(resp.Request) != origin.URL+"/end" {
+		t.Fatalf("final %d %s, want 200 %s/end", resp.StatusCode, requestURL(resp.Request), origin.URL)
+	}
+	if proxied.Load() != 1 || direct.Load() != 2 {
+		t.Errorf("proxy requests %d, direct requests %d, want 1 and 2", proxied.Load(), direct.Load())
+	}
+	chain := redirectChain(resp)
+	if len(chain) != 2 || chain[0].Url != origin.URL+"/start" || chain[1].Url != "http://hidden.onion/page" {
+		t.Errorf("redirect chain %+v", chain)
+	}
+}
+
+func TestRedirectLoopAcrossRoutes(t *testing.T) {
+	setTestConfig(t)
+	var origin *httptest.Server
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, origin.URL+"/start", http.StatusFound)
+	}))
+	defer proxySrv.Close()
+	origin = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://hidden.onion/page", http.StatusFound)
+	}))
+	defer origin.Close()
+	config.Conf.Proxy.Routes = []config.ProxyRoute{{Suffix: "onion", ProxyURL: proxySrv.URL}}
+
+	resp, err := httpRequest(origin.URL+"/start", nil)
+	if !errors.Is(err, ErrRedirectLoop) {
+		t.Fatalf("error %v, want %v", err, ErrRedirectLoop)
+	}
+	if resp == nil || len(redirectChain(resp)) == 0 {
+		t.Error("redirect loop without the response chain")
+	}
+}
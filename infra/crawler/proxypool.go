@@ -0,0 +1,369 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoProxyAvailable = errors.New("no proxy available")
+
+const (
+	strategyRoundRobin  = "round-robin"
+	strategyLeastLoaded = "least-loaded"
+)
+
+// errProxyDial falha ao conectar no próprio proxy, e não no destino através dele
+type errProxyDial struct {
+	err error
+}
+
+func (e *errProxyDial) Error() string { return "proxy unreachable: " + e.err.Error() }
+func (e *errProxyDial) Unwrap() error { return e.err }
+
+// proxyDialer conecta no proxy marcando as falhas com errProxyDial
+type proxyDialer struct {
+	dialer *net.Dialer
+}
+
+func (d *proxyDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *proxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, &errProxyDial{err: err}
+	}
+	return conn, nil
+}
+
+// proxyEndpoint proxy do pool com seu cliente HTTP e estado de saúde
+type proxyEndpoint struct {
+	// proxyURL nil indica acesso direto
+	proxyURL *url.URL
+	client   *http.Client
+
+	inFlight            int
+	requests            int64
+	failures            int64
+	bans                int64
+	consecutiveFailures int
+	dead                bool
+	// Bloqueios contados por host: um site que bloqueia o proxy suspende o proxy apenas para aquele host
+	banEvents map[string][]time.Time
+	ejected   map[string]time.Time
+}
+
+func (e *proxyEndpoint) available(now time.Time, host string) bool {
+	return !e.dead && !now.Before(e.ejected[host])
+}
+
+// proxyPool conjunto de proxies de uma rota, com rotação, verificação de saúde e detecção de bloqueio
+type proxyPool struct {
+	route proxyRoute
+
+	mu        sync.Mutex
+	endpoints []*proxyEndpoint
+	next      int
+}
+
+// ProxyStat estado de um proxy do pool, para monitoramento
+type ProxyStat struct {
+	Route    string `json:"route"`
+	Proxy    string `json:"proxy"`
+	InFlight int    `json:"in_flight"`
+	Requests int64  `json:"requests"`
+	Failures int64  `json:"failures"`
+	Bans     int64  `json:"bans"`
+	Dead     bool   `json:"dead"`
+	// Ejected hosts para os quais o proxy está suspenso, com o fim da suspensão
+	Ejected map[string]time.Time `json:"ejected,omitempty"`
+}
+
+func newProxyPool(route proxyRoute) *proxyPool {
+	pool := &proxyPool{route: route}
+	if route.direct {
+		pool.endpoints = []*proxyEndpoint{newProxyEndpoint(route, nil)}
+		return pool
+	}
+	if len(route.proxyURLs) == 0 {
+		// Sem proxy utilizável a rota não tem endpoints: as requisições falham em vez de sair sem proxy
+		log.Logger.Error("Proxy route without valid proxies, requests are refused", zap.String("Route", route.name))
+		return pool
+	}
+	for _, proxyURL := range route.proxyURLs {
+		pool.endpoints = append(pool.endpoints, newProxyEndpoint(route, proxyURL))
+	}
+	if config.Conf.Proxy.Pool.HealthCheckInterval > 0 {
+		go pool.healthCheckLoop(config.Conf.Proxy.Pool.HealthCheckInterval)
+	}
+	return pool
+}
+
+func newProxyEndpoint(route proxyRoute, proxyURL *url.URL) *proxyEndpoint {
+	return &proxyEndpoint{
+		proxyURL:  proxyURL,
+		banEvents: make(map[string][]time.Time),
+		ejected:   make(map[string]time.Time),
+		client: &http.Client{
+			Transport: &decodingTransport{base: &i2pTransport{
+				base:      newTransport(route, proxyURL),
+				httpProxy: proxyURL != nil && !isSocks(proxyURL),
+			}},
+			Timeout:       route.timeout,
+			CheckRedirect: checkRedirect,
+			Jar:           cookieJarFor(route),
+		},
+	}
+}
+
+// acquire escolhe um proxy disponível para o host conforme a estratégia da rota
+func (p *proxyPool) acquire(host string) (*proxyEndpoint, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+
+	var chosen *proxyEndpoint
+	if p.route.strategy == strategyLeastLoaded {
+		for _, ep := range p.endpoints {
+			if ep.available(now, host) && (chosen == nil || ep.inFlight < chosen.inFlight ||
+				(ep.inFlight == chosen.inFlight && ep.requests < chosen.requests)) {
+				chosen = ep
+			}
+		}
+	} else {
+		for i := range p.endpoints {
+			ep := p.endpoints[(p.next+i)%len(p.endpoints)]
+			if ep.available(now, host) {
+				chosen = ep
+				p.next = (p.next + i + 1) % len(p.endpoints)
+				break
+			}
+		}
+	}
+	if chosen == nil {
+		return nil, fmt.Errorf("%w for route %q", ErrNoProxyAvailable, p.route.name)
+	}
+	chosen.inFlight++
+	chosen.requests++
+	return chosen, nil
+}
+
+// release registra o resultado da requisição, removendo proxies inacessíveis e suspendendo os bloqueados pelo host.
+// MAX_FAILURES 0 desabilita a remoção por falhas.
+func (p *proxyPool) release(ep *proxyEndpoint, host string, err error, banned bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ep.inFlight--
+	if ep.proxyURL == nil {
+		return
+	}
+	poolCfg := config.Conf.Proxy.Pool
+
+	var dialErr *errProxyDial
+	if errors.As(err, &dialErr) {
+		ep.failures++
+		ep.consecutiveFailures++
+		if !ep.dead && poolCfg.MaxFailures > 0 && ep.consecutiveFailures >= poolCfg.MaxFailures {
+			ep.dead = true
+			log.Logger.Warn("Proxy removed from pool", zap.String("Route", p.route.name),
+				zap.String("Proxy", ep.proxyURL.Redacted()), zap.Error(err))
+		}
+	} else if err == nil {
+		ep.consecutiveFailures = 0
+	}
+
+	if banned {
+		now := time.Now()
+		ep.bans++
+		ep.pruneBans(now, poolCfg.BanWindow)
+		ep.banEvents[host] = append(ep.banEvents[host], now)
+		if poolCfg.BanThreshold > 0 && len(ep.banEvents[host]) >= poolCfg.BanThreshold {
+			ep.ejected[host] = now.Add(poolCfg.EjectDuration)
+			delete(ep.banEvents, host)
+			log.Logger.Warn("Proxy ejected for host after ban burst", zap.String("Route", p.route.name),
+				zap.String("Proxy", ep.proxyURL.Redacted()), zap.String("Host", host), zap.Time("Until", ep.ejected[host]))
+		}
+	}
+}
+
+// pruneBans descarta os bloqueios fora da janela e as suspensões vencidas
+func (e *proxyEndpoint) pruneBans(now time.Time, window time.Duration) {
+	for host, times := range e.banEvents {
+		events := times[:0]
+		for _, t := range times {
+			if now.Sub(t) < window {
+				events = append(events, t)
+			}
+		}
+		if len(events) == 0 {
+			delete(e.banEvents, host)
+		} else {
+			e.banEvents[host] = events
+		}
+	}
+	for host, until := range e.ejected {
+		if !now.Before(until) {
+			delete(e.ejected, host)
+		}
+	}
+}
+
+// do executa a requisição por um proxy do pool
+func (p *proxyPool) do(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(originURL(req).Hostname())
+	ep, err := p.acquire(host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ep.client.Do(req)
+	banned := err == nil && isBanResponse(resp)
+	p.release(ep, host, err, banned)
+	return resp, err
+}
+
+// isBanResponse detecta bloqueio pelo site: 403, 429 ou página de captcha
+func isBanResponse(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if resp.Header.Get("Cf-Mitigated") == "challenge" {
+		return true
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return false
+	}
+	head := bytes.ToLower(peekBody(resp, 4096))
+	for _, marker := range config.Conf.Proxy.Pool.BanMarkers {
+		if bytes.Contains(head, []byte(strings.ToLower(marker))) {
+			return true
+		}
+	}
+	return false
+}
+
+// peekBody lê os primeiros n bytes do corpo sem consumi-los
+func peekBody(resp *http.Response, n int) []byte {
+	buf := make([]byte, n)
+	k, _ := io.ReadFull(resp.Body, buf)
+	buf = buf[:k]
+	// Mantém o corpo decodificado para que a codificação e o tamanho comprimido continuem disponíveis
+	if body, ok := resp.Body.(*decodedBody); ok {
+		body.Reader = io.MultiReader(bytes.NewReader(buf), body.Reader)
+		return buf
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), resp.Body), resp.Body}
+	return buf
+}
+
+// healthCheckLoop verifica periodicamente os proxies, devolvendo ao pool os que voltaram a responder
+func (p *proxyPool) healthCheckLoop(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		p.mu.Lock()
+		endpoints := append([]*proxyEndpoint(nil), p.endpoints...)
+		p.mu.Unlock()
+
+		for _, ep := range endpoints {
+			err := p.healthCheck(ep)
+			p.mu.Lock()
+			switch {
+			case err != nil && !ep.dead:
+				ep.dead = true
+				log.Logger.Warn("Proxy failed health check", zap.String("Route", p.route.name),
+					zap.String("Proxy", ep.proxyURL.Redacted()), zap.Error(err))
+			case err == nil && ep.dead:
+				ep.dead = false
+				ep.consecutiveFailures = 0
+				log.Logger.Info("Proxy back to pool", zap.String("Route", p.route.name),
+					zap.String("Proxy", ep.proxyURL.Redacted()))
+			}
+			p.mu.Unlock()
+		}
+		log.Logger.Info("Proxy pool stats", zap.String("Route", p.route.name), zap.Any("Proxies", p.stats()))
+	}
+}
+
+// healthCheck verifica o proxy pela URL configurada ou, sem URL, pela conexão TCP
+func (p *proxyPool) healthCheck(ep *proxyEndpoint) error {
+	checkURL := config.Conf.Proxy.Pool.HealthCheckURL
+	if checkURL == "" {
+		conn, err := net.DialTimeout("tcp", ep.proxyURL.Host, config.Conf.HTTP.DialTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	resp, err := ep.client.Get(checkURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+	return nil
+}
+
+func (p *proxyPool) stats() []ProxyStat {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]ProxyStat, 0, len(p.endpoints))
+	now := time.Now()
+	for _, ep := range p.endpoints {
+		stat := ProxyStat{
+			Route:    p.route.name,
+			Proxy:    "direct",
+			InFlight: ep.inFlight,
+			Requests: ep.requests,
+			Failures: ep.failures,
+			Bans:     ep.bans,
+			Dead:     ep.dead,
+		}
+		for host, until := range ep.ejected {
+			if now.Before(until) {
+				if stat.Ejected == nil {
+					stat.Ejected = make(map[string]time.Time)
+				}
+				stat.Ejected[host] = until
+			}
+		}
+		if ep.proxyURL != nil {
+			stat.Proxy = ep.proxyURL.Redacted()
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// ProxyStats retorna o estado de todos os proxies em uso, agrupados por rota
+func ProxyStats() []ProxyStat {
+	defaultFetcher.mu.Lock()
+	pools := make([]*proxyPool, 0, len(defaultFetcher.pools))
+	for _, pool := range defaultFetcher.pools {
+		pools = append(pools, pool)
+	}
+	defaultFetcher.mu.Unlock()
+
+	var stats []ProxyStat
+	for _, pool := range pools {
+		stats = append(stats, pool.stats()...)
+	}
+	return stats
+}
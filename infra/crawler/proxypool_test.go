@@ -0,0 +1,55 @@
+package crawler
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gabrielmoura/WebCrawler/config"
+)
+
+func testPool(t *testing.T) (*proxyPool, *proxyEndpoint) {
+	setTestConfig(t)
+	proxyURL, _ := url.Parse("socks5h://127.0.0.1:9050")
+	route := proxyRoute{name: "onion", proxyURLs: []*url.URL{proxyURL}}
+	ep := newProxyEndpoint(route, proxyURL)
+	return &proxyPool{route: route, endpoints: []*proxyEndpoint{ep}}, ep
+}
+
+func TestProxyBansCountedPerHost(t *testing.T) {
+	pool, ep := testPool(t)
+	config.Conf.Proxy.Pool.BanThreshold = 2
+	config.Conf.Proxy.Pool.EjectDuration = time.Minute
+
+	pool.release(ep, "a.onion", nil, true)
+	pool.release(ep, "b.onion", nil, true)
+	now := time.Now()
+	if !ep.available(now, "a.onion") || !ep.available(now, "b.onion") {
+		t.Fatal("proxy ejected before the threshold of a single host")
+	}
+	pool.release(ep, "a.onion", nil, true)
+	if ep.available(now, "a.onion") {
+		t.Error("proxy still available for the host that banned it")
+	}
+	if !ep.available(now, "b.onion") {
+		t.Error("proxy ejected for a host that did not ban it")
+	}
+	for _, host := range []string{"a.onion", "b.onion"} {
+		if _, err := pool.acquire(host); (err != nil) != (host == "a.onion") {
+			t.Errorf("acquire %s: %v", host, err)
+		}
+	}
+}
+
+func TestProxyMaxFailuresZeroDisabled(t *testing.T) {
+	pool, ep := testPool(t)
+	config.Conf.Proxy.Pool.MaxFailures = 0
+	for i := 0; i < 5; i++ {
+		ep.inFlight++
+		pool.release(ep, "a.onion", &errProxyDial{err: errors.New("refused")}, false)
+	}
+	if ep.dead {
+		t.Error("proxy removed with MAX_FAILURES 0")
+	}
+}
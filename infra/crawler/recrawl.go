@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"github.com/gabrielmoura/WebCrawler/infra/db"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// contentHash hash SHA-256 do corpo, usado para detectar páginas sem alteração
+func contentHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// conditionalHeaders cabeçalhos de requisição condicional a partir da última busca
+func conditionalHeaders(state data.FetchState) http.Header {
+	header := http.Header{}
+	if state.ETag != "" {
+		header.Set("If-None-Match", state.ETag)
+	}
+	if state.LastModified != "" {
+		header.Set("If-Modified-Since", state.LastModified)
+	}
+	return header
+}
+
+// setValidators guarda os validadores e o hash da resposta no resultado
+func (r *fetchResult) setValidators(header http.Header, body []byte) {
+	r.ETag = header.Get("ETag")
+	r.LastModified = header.Get("Last-Modified")
+	r.ContentHash = contentHash(body)
+}
+
+// markUnchanged registra uma revisita sem alteração (304 ou mesmo hash): só o horário da verificação é atualizado
+// e o intervalo até a próxima visita aumenta
+func markUnchanged(pageUrl string, depth int, state data.FetchState, header http.Header) {
+	now := time.Now()
+	state.LastChecked = now
+	if etag := header.Get("ETag"); etag != "" {
+		state.ETag = etag
+	}
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		state.LastModified = lastModified
+	}
+	scheduleNextVisit(pageUrl, depth, &state, false)
+	if err := cache.SetFetchState(pageUrl, state); err != nil {
+		log.Logger.Error("error saving fetch state", zap.String("URL", pageUrl), zap.Error(err))
+	}
+	if err := db.TouchPage(pageUrl, now); err != nil {
+		log.Logger.Error("error updating last checked", zap.String("URL", pageUrl), zap.Error(err))
+	}
+}
+
+// saveFetchState grava os validadores da página buscada para a próxima revisita.
+// Uma página já conhecida que foi extraída de novo mudou, e o intervalo até a próxima visita diminui.
+func saveFetchState(pageUrl string, depth int, page *data.Page) {
+	state, changed := cache.GetFetchState(pageUrl)
+	state.ETag = page.ETag
+	state.LastModified = page.LastModified
+	state.ContentHash = page.ContentHash
+	state.LastChecked = page.LastChecked
+	state.LastChanged = page.LastChecked
+	scheduleNextVisit(pageUrl, depth, &state, changed)
+	if err := cache.SetFetchState(pageUrl, state); err != nil {
+		log.Logger.Error("error saving fetch state", zap.String("URL", pageUrl), zap.Error(err))
+	}
+}
+
+// fetchStateURL URL sob a qual estão o estado da busca e a página gravada: a própria URL ou,
+// se ela foi redirecionada, a URL final
+func fetchStateURL(pageUrl string) string {
+	if _, ok := cache.GetFetchState(pageUrl); ok {
+		return pageUrl
+	}
+	if finalUrl, ok := cache.GetFetchAlias(pageUrl); ok {
+		return finalUrl
+	}
+	return pageUrl
+}
+
+// aliasFetchState liga a URL pedida à URL final, que passa a guardar o estado e o agendamento das revisitas;
+// o estado e os agendamentos anteriores da URL pedida são removidos
+func aliasFetchState(pageUrl, finalUrl string) {
+	if pageUrl == finalUrl {
+		return
+	}
+	if err := cache.SetFetchAlias(pageUrl, finalUrl); err != nil {
+		log.Logger.Error("error saving fetch alias", zap.String("URL", pageUrl), zap.Error(err))
+		return
+	}
+	state, ok := cache.GetFetchState(pageUrl)
+	if !ok {
+		return
+	}
+	for _, at := range []time.Time{state.NextVisit, state.RetryAt} {
+		if !at.IsZero() {
+			if err := cache.UnscheduleVisit(pageUrl, at); err != nil {
+				log.Logger.Error("error unscheduling visit", zap.String("URL", pageUrl), zap.Error(err))
+			}
+		}
+	}
+	if err := cache.DeleteFetchState(pageUrl); err != nil {
+		log.Logger.Error("error deleting fetch state", zap.String("URL", pageUrl), zap.Error(err))
+	}
+}
+
+// handleUnchanged revisita sem alteração: a página não é extraída de novo, mas seus links armazenados voltam à fila
+func handleUnchanged(pageUrl string, depth int, seed config.Seed) {
+	log.Logger.Info("Page not modified", zap.String("URL", pageUrl))
+	SetVisited(pageUrl)
+	if page := GetPage(fetchStateURL(pageUrl)); page != nil {
+		handleAddToQueue(page.Links, depth+1, seed, fetchStateURL(pageUrl))
+	}
+}
@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+)
+
+func TestAliasFetchStateMovesToFinalURL(t *testing.T) {
+	setTestConfig(t)
+	setTestCache(t)
+	requested, final := "http://alias.example/old", "http://alias.example/new"
+	next := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := cache.SetFetchState(requested, data.FetchState{ETag: `"old"`, NextVisit: next}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.ScheduleVisit(requested, 1, time.Time{}, next); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SetFetchState(final, data.FetchState{ETag: `"new"`}); err != nil {
+		t.Fatal(err)
+	}
+
+	aliasFetchState(requested, final)
+
+	if got := fetchStateURL(requested); got != final {
+		t.Errorf("fetch state URL %q, want %q", got, final)
+	}
+	if _, ok := cache.GetFetchState(requested); ok {
+		t.Error("requested URL kept its own fetch state")
+	}
+	due, err := cache.PopDueVisits(next, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, visit := range due {
+		if visit.Url == requested {
+			t.Error("requested URL still scheduled")
+		}
+	}
+	if got := fetchStateURL(final); got != final {
+		t.Errorf("final URL resolves to %q", got)
+	}
+}
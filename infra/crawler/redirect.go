@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"net/http"
+	"net/url"
+)
+
+var (
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrRedirectLoop     = errors.New("redirect loop")
+)
+
+// checkRedirect política de redirecionamento do cliente: limita o número de saltos e detecta ciclos
+// na cadeia inteira, inclusive nos saltos feitos por outras rotas.
+// Um salto para outra rota de proxy interrompe o cliente da rota atual; doRequest o envia pela nova rota.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	chain := via
+	if req.Response != nil {
+		chain = redirectVia(req.Response)
+	}
+	if err := redirectLimits(req, chain); err != nil {
+		return err
+	}
+	if len(via) > 0 && routeFor(req.URL).name != routeFor(via[0].URL).name {
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
+
+// redirectLimits limita o número de saltos e detecta ciclos na cadeia via
+func redirectLimits(req *http.Request, via []*http.Request) error {
+	for _, prev := range via {
+		if requestURL(prev) == requestURL(req) {
+			return fmt.Errorf("%w: %s", ErrRedirectLoop, requestURL(req))
+		}
+	}
+	if len(via) > config.Conf.HTTP.MaxRedirects {
+		return fmt.Errorf("%w: %d", ErrTooManyRedirects, len(via))
+	}
+	return nil
+}
+
+// isRedirectStatus status de redirecionamento seguidos pelo cliente
+func isRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// requestURL URL da requisição com o cabeçalho Host, quando ele difere do host da URL
+func requestURL(req *http.Request) string {
+	return originURL(req).String()
+}
+
+// originURL URL da requisição com o host do cabeçalho Host, quando definido e diferente do host da URL
+// (ex: host .i2p trocado pelo endereço b32); usada para escolher autenticação e cabeçalhos do domínio
+func originURL(req *http.Request) *url.URL {
+	if req.Host == "" || req.Host == req.URL.Host {
+		return req.URL
+	}
+	u := *req.URL
+	u.Host = req.Host
+	return &u
+}
+
+// redirectChain reconstrói, em ordem, os redirecionamentos seguidos até a resposta
+func redirectChain(resp *http.Response) []data.Redirect {
+	var chain []data.Redirect
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		prev := req.Response
+		chain = append([]data.Redirect{{
+			Url:      requestURL(prev.Request),
+			Status:   prev.StatusCode,
+			Location: prev.Header.Get("Location"),
+		}}, chain...)
+	}
+	return chain
+}
+
+// setRedirectsVisited marca como visitadas todas as URLs da cadeia de redirecionamentos
+func setRedirectsVisited(chain []data.Redirect) {
+	for _, hop := range chain {
+		SetVisited(hop.Url)
+	}
+}
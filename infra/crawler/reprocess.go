@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"github.com/gabrielmoura/WebCrawler/infra/warc"
+	"go.uber.org/zap"
+	"io"
+	"strconv"
+)
+
+// ReprocessWarc executa a extração sobre as respostas gravadas em um arquivo WARC,
+// sem acesso à rede, atualizando páginas e links no banco de dados.
+// Retorna o número de páginas gravadas.
+func ReprocessWarc(path string) (int, error) {
+	reader, err := warc.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("error opening WARC %s: %w", path, err)
+	}
+	defer reader.Close()
+
+	var count int
+	// O registro metadata (com a profundidade) é gravado depois do response,
+	// por isso cada response só é processado quando o próximo response ou o fim do arquivo é lido.
+	var pending *warc.Record
+	depth := 0
+	flush := func() {
+		if pending == nil {
+			return
+		}
+		if reprocessRecord(pending, depth) {
+			count++
+		}
+		pending, depth = nil, 0
+	}
+
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			flush()
+			return count, fmt.Errorf("error reading WARC %s: %w", path, err)
+		}
+		switch record.Type() {
+		case warc.TypeResponse:
+			flush()
+			pending = record
+		case warc.TypeMetadata:
+			if pending != nil && record.Header.Get("WARC-Concurrent-To") == pending.ID() {
+				depth, _ = strconv.Atoi(record.Fields()["depth"])
+			}
+		}
+	}
+	flush()
+	return count, nil
+}
+
+// reprocessRecord extrai e grava a página de um registro response, retornando se ela foi gravada
+func reprocessRecord(record *warc.Record, depth int) bool {
+	pageUrl := record.TargetURI()
+	resp, body, err := record.HTTPResponse()
+	if err != nil {
+		log.Logger.Debug("error reading WARC response", zap.String("URL", pageUrl), zap.Error(err))
+		return false
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false
+	}
+	if !isAllowedMIME(resp.Header.Get("Content-Type"), config.AcceptableMimeTypes) {
+		return false
+	}
+	// O corpo é arquivado como recebido, ainda comprimido
+	body, err = decodeReceived(resp.Header, body, record.Header.Get("WARC-Truncated") != "")
+	if err != nil {
+		log.Logger.Debug("error decoding WARC response", zap.String("URL", pageUrl), zap.Error(err))
+		return false
+	}
+
+	result, err := parseBody(body)
+	if err != nil {
+		log.Logger.Debug("error parsing WARC response", zap.String("URL", pageUrl), zap.Error(err))
+		return false
+	}
+	result.WarcRecordID = record.ID()
+	result.setValidators(resp.Header, body)
+
+	dataPage, links, err := buildPage(pageUrl, depth, result)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return false
+	}
+	if date := record.Date(); !date.IsZero() {
+		dataPage.Timestamp = date
+	}
+
+	SetPage(pageUrl, dataPage)
+	SetLinks(pageUrl, links)
+	return true
+}
@@ -0,0 +1,126 @@
+package crawler
+
+import (
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"go.uber.org/zap"
+	"time"
+)
+
+// nextInterval adapta o intervalo de revisita à taxa de mudança observada:
+// dividido por Factor quando a página mudou e multiplicado quando não mudou, dentro dos limites configurados
+func nextInterval(state data.FetchState, changed bool) time.Duration {
+	cfg := config.Conf.Recrawl
+	interval := state.Interval
+	switch {
+	case interval <= 0:
+		interval = cfg.InitialInterval
+	case changed:
+		interval = time.Duration(float64(interval) / cfg.Factor)
+	default:
+		interval = time.Duration(float64(interval) * cfg.Factor)
+	}
+	if interval < cfg.MinInterval {
+		interval = cfg.MinInterval
+	}
+	if cfg.MaxInterval > 0 && interval > cfg.MaxInterval {
+		interval = cfg.MaxInterval
+	}
+	return interval
+}
+
+// scheduleNextVisit calcula a próxima visita da página e, com Recrawl habilitado, a agenda.
+// A busca terminou, então a revisita provisória (RetryAt) é removida.
+func scheduleNextVisit(pageUrl string, depth int, state *data.FetchState, changed bool) {
+	prev, retryAt := state.NextVisit, state.RetryAt
+	state.Interval = nextInterval(*state, changed)
+	state.NextVisit = state.LastChecked.Add(state.Interval)
+	state.Depth = depth
+	state.RetryAt, state.Failures = time.Time{}, 0
+	if !config.Conf.Recrawl.Enabled {
+		return
+	}
+	if err := cache.ScheduleVisit(pageUrl, depth, prev, state.NextVisit); err != nil {
+		log.Logger.Error("error scheduling visit", zap.String("URL", pageUrl), zap.Error(err))
+	}
+	if !retryAt.IsZero() && !retryAt.Equal(state.NextVisit) {
+		if err := cache.UnscheduleVisit(pageUrl, retryAt); err != nil {
+			log.Logger.Error("error unscheduling visit", zap.String("URL", pageUrl), zap.Error(err))
+		}
+	}
+}
+
+// retryDelay recuo da revisita provisória: MinInterval dobrado a cada revisita seguida sem sucesso, até MaxInterval
+func retryDelay(failures int) time.Duration {
+	cfg := config.Conf.Recrawl
+	delay := max(cfg.MinInterval, time.Minute)
+	for i := 0; i < failures && (cfg.MaxInterval <= 0 || delay < cfg.MaxInterval); i++ {
+		delay *= 2
+	}
+	if cfg.MaxInterval > 0 && delay > cfg.MaxInterval {
+		delay = cfg.MaxInterval
+	}
+	return delay
+}
+
+// holdDueVisit agenda uma revisita provisória para a página retirada do agendamento. Se a busca falhar
+// (erro de rede, status, corpo grande) ela continua agendada, com recuo; a busca concluída a substitui.
+func holdDueVisit(visit cache.QueueType) {
+	state, ok := cache.GetFetchState(visit.Url)
+	if !ok {
+		// Páginas ainda não buscadas, adiadas pelo controle de taxa
+		return
+	}
+	prev := state.RetryAt
+	state.RetryAt = time.Now().Add(retryDelay(state.Failures))
+	state.Failures++
+	if err := cache.ScheduleVisit(visit.Url, visit.Depth, prev, state.RetryAt); err != nil {
+		log.Logger.Error("error scheduling visit", zap.String("URL", visit.Url), zap.Error(err))
+		return
+	}
+	if err := cache.SetFetchState(visit.Url, state); err != nil {
+		log.Logger.Error("error saving fetch state", zap.String("URL", visit.Url), zap.Error(err))
+	}
+}
+
+// enqueueDueVisits devolve à fila as páginas com revisita vencida, retornando quantas foram adicionadas
+func enqueueDueVisits() int {
+	due, err := cache.PopDueVisits(time.Now(), 1000)
+	if err != nil {
+		log.Logger.Error("error reading due visits", zap.Error(err))
+		return 0
+	}
+	for _, visit := range due {
+		if config.Conf.Recrawl.Enabled {
+			holdDueVisit(visit)
+		}
+		if err := cache.AddToQueue(visit.Url, visit.Depth, queuePriority(visit.Url)); err != nil {
+			log.Logger.Error("error adding link to queue", zap.String("Link", visit.Url), zap.Error(err))
+		}
+	}
+	if len(due) > 0 {
+		log.Logger.Info("Recrawl due pages", zap.Int("Count", len(due)))
+	}
+	return len(due)
+}
+
+// waitDueVisits aguarda, com a fila vazia, até haver páginas com revisita vencida.
+// Sem Recrawl aguarda apenas enquanto houver páginas adiadas pelo controle de taxa.
+func waitDueVisits() {
+	for enqueueDueVisits() == 0 {
+		if jobPaused() || defaultBudget.exhausted() != "" {
+			return
+		}
+		wait := config.Conf.Recrawl.CheckInterval
+		if !config.Conf.Recrawl.Enabled {
+			next, ok := cache.NextScheduledVisit()
+			if !ok {
+				return
+			}
+			wait = min(wait, time.Until(next)+time.Second)
+		}
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,203 @@
+package crawler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"github.com/gabrielmoura/WebCrawler/infra/scope"
+	"github.com/gabrielmoura/go/pkg/ternary"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var ErrInvalidSeed = errors.New("invalid seed")
+
+var (
+	seedsMu sync.RWMutex
+	// seeds URLs iniciais registradas, pela tag
+	seeds = make(map[string]config.Seed)
+)
+
+// LoadSeeds reúne as URLs iniciais de SEEDS (ou das flags -seed/-url) e do arquivo de seeds ("-" lê da entrada padrão).
+// Sem nenhuma é usada URL.
+func LoadSeeds() ([]config.Seed, error) {
+	list := append([]config.Seed(nil), config.Conf.Seeds...)
+	if config.Conf.SeedFile != "" {
+		fromFile, err := readSeedFile(config.Conf.SeedFile)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, fromFile...)
+	}
+	if len(list) == 0 && config.Conf.InicialURL != "" {
+		list = append(list, config.Seed{URL: config.Conf.InicialURL})
+	}
+
+	unique := make([]config.Seed, 0, len(list))
+	known := make(map[string]bool)
+	for _, seed := range list {
+		seed.URL = strings.TrimSpace(seed.URL)
+		if seed.URL == "" || known[seed.URL] {
+			continue
+		}
+		known[seed.URL] = true
+		if seed.Tag == "" {
+			seed.Tag = seed.URL
+		}
+		if err := validateSeed(seed); err != nil {
+			return nil, err
+		}
+		unique = append(unique, seed)
+	}
+	return unique, nil
+}
+
+// validateSeed verifica SCOPE (same-host, same-domain ou vazio) e PRIORITY (0 a 999)
+func validateSeed(seed config.Seed) error {
+	switch seed.Scope {
+	case "", scope.PresetSameHost, scope.PresetSameDomain:
+	default:
+		return fmt.Errorf("%w %s: SCOPE %q, use same-host or same-domain", ErrInvalidSeed, seed.URL, seed.Scope)
+	}
+	if seed.Priority < 0 || seed.Priority > cache.MaxPriority {
+		return fmt.Errorf("%w %s: PRIORITY %d, use 0 to %d", ErrInvalidSeed, seed.URL, seed.Priority, cache.MaxPriority)
+	}
+	return nil
+}
+
+// readSeedFile lê seeds de um arquivo YAML (chave SEEDS, com configurações por seed) ou de texto, uma URL por linha
+func readSeedFile(path string) ([]config.Seed, error) {
+	if path == "-" {
+		return readSeedLines(os.Stdin)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		vip := viper.New()
+		vip.SetConfigFile(path)
+		if err := vip.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading seed file: %w", err)
+		}
+		var file struct {
+			Seeds []config.Seed `mapstructure:"SEEDS"`
+		}
+		if err := vip.Unmarshal(&file); err != nil {
+			return nil, fmt.Errorf("error reading seed file: %w", err)
+		}
+		return file.Seeds, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading seed file: %w", err)
+	}
+	defer file.Close()
+	return readSeedLines(file)
+}
+
+// readSeedLines uma URL por linha, ignorando linhas vazias e comentários (#)
+func readSeedLines(r io.Reader) ([]config.Seed, error) {
+	var list []config.Seed
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, config.Seed{URL: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading seeds: %w", err)
+	}
+	return list, nil
+}
+
+// registerSeed registra a seed pela tag e o seu host para os presets de escopo
+func registerSeed(seed config.Seed) {
+	seedsMu.Lock()
+	seeds[seed.Tag] = seed
+	seedsMu.Unlock()
+	scope.AddSeed(seed.URL)
+}
+
+// addSeed registra a seed, a marca como origem de si mesma e a adiciona à fila se ainda não foi visitada
+func addSeed(seed config.Seed) {
+	registerSeed(seed)
+
+	if err := cache.SetSeed(seed.URL, seed.Tag); err != nil {
+		log.Logger.Error("error tagging seed", zap.String("URL", seed.URL), zap.Error(err))
+	}
+	if GetVisited(seed.URL) {
+		return
+	}
+	if err := cache.AddToQueue(seed.URL, 0, seed.Priority); err != nil {
+		log.Logger.Error("error adding seed to queue", zap.String("URL", seed.URL), zap.Error(err))
+	}
+}
+
+// seedFor retorna a seed que levou à URL
+func seedFor(pageUrl string) (config.Seed, bool) {
+	tag, ok := cache.GetSeed(pageUrl)
+	if !ok {
+		return config.Seed{}, false
+	}
+	seedsMu.RLock()
+	defer seedsMu.RUnlock()
+	seed, ok := seeds[tag]
+	if !ok {
+		// Seed de uma execução anterior, sem configurações próprias
+		return config.Seed{URL: tag, Tag: tag}, true
+	}
+	return seed, true
+}
+
+// ExplainScope explica se o link seria enfileirado na profundidade, como em handleAddToQueue: as regras de
+// SCOPE e depois o SCOPE da seed que levou ao link (seedFor). Um link ainda sem seed é aceito se o SCOPE
+// de alguma das seeds informadas o aceita.
+func ExplainScope(link string, depth int, list []config.Seed) scope.Decision {
+	for _, seed := range list {
+		registerSeed(seed)
+	}
+	decision := scope.Explain(link, depth)
+	if !decision.Allowed {
+		return decision
+	}
+	candidates := list
+	if seed, ok := seedFor(link); ok {
+		candidates = []config.Seed{seed}
+	}
+	for _, seed := range candidates {
+		allowed := scope.SameSeed(seed.Scope, link, seed.URL)
+		decision.Trace = append(decision.Trace, fmt.Sprintf("seed %s (SCOPE %q): %s", seed.Tag, seed.Scope,
+			ternary.Ternary(allowed, "match", "mismatch")))
+		if allowed {
+			decision.Reason = fmt.Sprintf("%s, seed %s", decision.Reason, seed.Tag)
+			return decision
+		}
+	}
+	if len(candidates) > 0 {
+		decision.Allowed = false
+		decision.Reason = "outside the SCOPE of the seeds"
+	}
+	return decision
+}
+
+// maxDepthFor profundidade máxima das páginas da seed, ou a global
+func maxDepthFor(seed config.Seed) int {
+	if seed.MaxDepth != nil {
+		return *seed.MaxDepth
+	}
+	return *config.MaxDepth
+}
+
+// queuePriority prioridade na fila das páginas da seed que levou à URL
+func queuePriority(pageUrl string) int {
+	seed, _ := seedFor(pageUrl)
+	return seed.Priority
+}
@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/scope"
+)
+
+func TestLoadSeedsValidates(t *testing.T) {
+	setTestConfig(t)
+	tests := []struct {
+		name  string
+		seed  config.Seed
+		valid bool
+	}{
+		{name: "defaults", seed: config.Seed{URL: "http://a.example/"}, valid: true},
+		{name: "same-domain", seed: config.Seed{URL: "http://a.example/", Scope: "same-domain", Priority: 999}, valid: true},
+		{name: "unknown scope", seed: config.Seed{URL: "http://a.example/", Scope: "same-site"}},
+		{name: "negative priority", seed: config.Seed{URL: "http://a.example/", Priority: -1}},
+		{name: "priority too high", seed: config.Seed{URL: "http://a.example/", Priority: 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Conf.Seeds = []config.Seed{tt.seed}
+			_, err := LoadSeeds()
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidSeed) {
+				t.Errorf("error %v, want %v", err, ErrInvalidSeed)
+			}
+		})
+	}
+}
+
+func TestQueueKeepsOneEntryPerURL(t *testing.T) {
+	setTestConfig(t)
+	setTestCache(t)
+	link := "http://queue.example/page"
+	for _, priority := range []int{5, 1, 9, 9} {
+		if err := cache.AddToQueue(link, 2, priority); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links, err := cache.GetFromQueueV2(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []cache.QueueType
+	for _, item := range links {
+		if item.Url == link {
+			found = append(found, item)
+		}
+	}
+	if len(found) != 1 || found[0].Priority != 9 {
+		t.Fatalf("queued %+v, want one entry with priority 9", found)
+	}
+	// Depois de retirada, a URL pode voltar à fila
+	if err := cache.AddToQueue(link, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	links, _ = cache.GetFromQueueV2(1000)
+	if len(links) != 1 || links[0].Url != link {
+		t.Errorf("requeued %+v", links)
+	}
+}
+
+func TestExplainScopeUsesSeedScope(t *testing.T) {
+	setTestConfig(t)
+	setTestCache(t)
+	if err := scope.Init(); err != nil {
+		t.Fatal(err)
+	}
+	list := []config.Seed{
+		{URL: "http://host.explain.example/", Tag: "host", Scope: scope.PresetSameHost},
+		{URL: "http://domain.other-explain.example/", Tag: "domain", Scope: scope.PresetSameDomain},
+	}
+	tests := []struct {
+		link    string
+		allowed bool
+	}{
+		{"http://host.explain.example/page", true},
+		{"http://sub.explain.example/page", false},
+		{"http://www.other-explain.example/page", true},
+		{"http://unrelated.example/page", false},
+	}
+	for _, tt := range tests {
+		if decision := ExplainScope(tt.link, 1, list); decision.Allowed != tt.allowed {
+			t.Errorf("%s: allowed %v (%s), want %v", tt.link, decision.Allowed, decision.Reason, tt.allowed)
+		}
+	}
+	// Um link já marcado é avaliado apenas pelo SCOPE da sua seed
+	tagged := "http://www.other-explain.example/tagged"
+	if err := cache.SetSeed(tagged, "host"); err != nil {
+		t.Fatal(err)
+	}
+	if decision := ExplainScope(tagged, 1, list); decision.Allowed {
+		t.Errorf("%s allowed by %s, want excluded by the seed host", tagged, decision.Reason)
+	}
+}
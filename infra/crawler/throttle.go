@@ -0,0 +1,281 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/cache"
+	"github.com/gabrielmoura/WebCrawler/infra/db"
+	"github.com/gabrielmoura/WebCrawler/infra/log"
+	"github.com/gabrielmoura/go/pkg/ternary"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrThrottled a busca foi adiada pelo controle de taxa do host
+var ErrThrottled = errors.New("host throttled")
+
+// throttledError busca adiada até until
+type throttledError struct {
+	host  string
+	until time.Time
+}
+
+func (e *throttledError) Error() string {
+	return fmt.Sprintf("%s: %s until %s", ErrThrottled, e.host, e.until.Format(time.RFC3339))
+}
+func (e *throttledError) Unwrap() error { return ErrThrottled }
+
+// hostThrottle estado do controle de taxa de um host
+type hostThrottle struct {
+	// delay intervalo atual entre requisições ao host
+	delay time.Duration
+	// next horário a partir do qual a próxima requisição pode ser enviada
+	next                time.Time
+	pausedUntil         time.Time
+	consecutiveFailures int
+	requests            int64
+	throttled           int64
+}
+
+// throttler controle de taxa adaptativo (AIMD) por host: o intervalo entre requisições é multiplicado
+// em 429/503, erros 5xx e timeouts e reduzido aos poucos a cada sucesso
+type throttler struct {
+	mu    sync.Mutex
+	hosts map[string]*hostThrottle
+	// retries novas tentativas já feitas por URL
+	retries sync.Map
+}
+
+var defaultThrottler = &throttler{hosts: make(map[string]*hostThrottle)}
+
+func throttleEnabled() bool {
+	return config.Conf.Throttle != nil && config.Conf.Throttle.Enabled
+}
+
+func (t *throttler) host(name string) *hostThrottle {
+	h, ok := t.hosts[name]
+	if !ok {
+		h = &hostThrottle{delay: config.Conf.Throttle.MinDelay}
+		t.hosts[name] = h
+	}
+	return h
+}
+
+// wait aguarda a vez da requisição ao host. Hosts pausados, ou com espera maior que MaxDelay,
+// retornam um throttledError para que a página seja adiada sem ocupar o worker.
+func (t *throttler) wait(link *url.URL) error {
+	if !throttleEnabled() {
+		return nil
+	}
+	name := strings.ToLower(link.Hostname())
+	now := time.Now()
+
+	t.mu.Lock()
+	h := t.host(name)
+	if now.Before(h.pausedUntil) {
+		t.mu.Unlock()
+		return &throttledError{host: name, until: h.pausedUntil}
+	}
+	start := now
+	if h.next.After(start) {
+		start = h.next
+	}
+	if start.Sub(now) > config.Conf.Throttle.MaxDelay {
+		t.mu.Unlock()
+		return &throttledError{host: name, until: start}
+	}
+	h.next = start.Add(h.delay)
+	h.requests++
+	t.mu.Unlock()
+
+	time.Sleep(start.Sub(now))
+	return nil
+}
+
+// success reduz o intervalo do host em RecoveryStep, até MinDelay
+func (t *throttler) success(link *url.URL) {
+	cfg := config.Conf.Throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.host(strings.ToLower(link.Hostname()))
+	h.consecutiveFailures = 0
+	h.delay -= cfg.RecoveryStep
+	if h.delay < cfg.MinDelay {
+		h.delay = cfg.MinDelay
+	}
+}
+
+// failure multiplica o intervalo do host por Factor e respeita o Retry-After. Após PauseAfter falhas
+// seguidas o host é pausado por PauseDuration. Retorna o horário em que o host pode ser buscado de novo.
+func (t *throttler) failure(link *url.URL, retryAfter time.Duration) time.Time {
+	cfg := config.Conf.Throttle
+	name := strings.ToLower(link.Hostname())
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.host(name)
+	h.throttled++
+	h.consecutiveFailures++
+	h.delay = time.Duration(float64(h.delay) * cfg.Factor)
+	if h.delay < cfg.RecoveryStep {
+		h.delay = cfg.RecoveryStep
+	}
+	if h.delay > cfg.MaxDelay {
+		h.delay = cfg.MaxDelay
+	}
+	next := now.Add(h.delay)
+	if retryAfter > 0 && now.Add(retryAfter).After(next) {
+		next = now.Add(retryAfter)
+	}
+	if next.After(h.next) {
+		h.next = next
+	}
+	if cfg.PauseAfter > 0 && h.consecutiveFailures >= cfg.PauseAfter {
+		h.pausedUntil = now.Add(cfg.PauseDuration)
+		h.consecutiveFailures = 0
+		log.Logger.Warn("Host paused after failures", zap.String("Host", name), zap.Time("Until", h.pausedUntil))
+	}
+	if h.pausedUntil.After(h.next) {
+		return h.pausedUntil
+	}
+	return h.next
+}
+
+// recordFetch registra o resultado da busca no controle de taxa. Em 429/503 e timeouts retorna
+// um throttledError enquanto a URL ainda tiver tentativas (MaxRetries); outros erros 5xx só desaceleram o host.
+func recordFetch(pageUrl string, resp *http.Response, err error) error {
+	if !throttleEnabled() {
+		return nil
+	}
+	link, parseErr := url.Parse(pageUrl)
+	if parseErr != nil {
+		return nil
+	}
+	var retryAfter time.Duration
+	switch {
+	case err != nil && isTimeout(err):
+	case err != nil:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode >= http.StatusInternalServerError:
+		defaultThrottler.failure(link, 0)
+		return nil
+	default:
+		defaultThrottler.success(link)
+		defaultThrottler.retries.Delete(pageUrl)
+		return nil
+	}
+
+	until := defaultThrottler.failure(link, retryAfter)
+	value, _ := defaultThrottler.retries.LoadOrStore(pageUrl, new(atomic.Int32))
+	if int(value.(*atomic.Int32).Add(1)) > config.Conf.Throttle.MaxRetries {
+		defaultThrottler.retries.Delete(pageUrl)
+		return nil
+	}
+	return &throttledError{host: strings.ToLower(link.Hostname()), until: until}
+}
+
+// isTimeout verifica se o erro é um timeout da requisição
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// parseRetryAfter interpreta o Retry-After em segundos ou como data HTTP
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// deferVisit agenda a página para quando o host puder ser buscado de novo
+func deferVisit(pageUrl string, depth int, until time.Time) {
+	if err := cache.ScheduleVisit(pageUrl, depth, time.Time{}, until); err != nil {
+		log.Logger.Error("error deferring visit", zap.String("URL", pageUrl), zap.Error(err))
+		return
+	}
+	log.Logger.Debug("Visit deferred", zap.String("URL", pageUrl), zap.Time("Until", until))
+}
+
+// pendingDeferrals verifica se ainda há buscas adiadas a aguardar. Os adiamentos ficam no agendamento do cache,
+// então também valem para a execução retomada depois de reiniciar o crawler.
+func pendingDeferrals() bool {
+	_, ok := cache.NextScheduledVisit()
+	return ok
+}
+
+// HostStat estado do controle de taxa de um host, para monitoramento
+type HostStat struct {
+	Host        string        `json:"host"`
+	Delay       time.Duration `json:"delay"`
+	Requests    int64         `json:"requests"`
+	Throttled   int64         `json:"throttled"`
+	PausedUntil time.Time     `json:"paused_until"`
+}
+
+// ThrottleStats retorna os hosts desacelerados ou pausados
+func ThrottleStats() []HostStat {
+	if !throttleEnabled() {
+		return nil
+	}
+	now := time.Now()
+	defaultThrottler.mu.Lock()
+	defer defaultThrottler.mu.Unlock()
+	var stats []HostStat
+	for name, h := range defaultThrottler.hosts {
+		if h.delay <= config.Conf.Throttle.MinDelay && !now.Before(h.pausedUntil) {
+			continue
+		}
+		stats = append(stats, HostStat{
+			Host:        name,
+			Delay:       h.delay,
+			Requests:    h.requests,
+			Throttled:   h.throttled,
+			PausedUntil: h.pausedUntil,
+		})
+	}
+	return stats
+}
+
+// throttleStatsLoop registra periodicamente os hosts desacelerados e os grava no job, para o `job list`
+func throttleStatsLoop(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		stats := ThrottleStats()
+		if len(stats) > 0 {
+			log.Logger.Info("Throttled hosts", zap.Any("Hosts", stats))
+		}
+		saveThrottleStats(stats)
+	}
+}
+
+// saveThrottleStats grava no job os hosts desacelerados; uma lista vazia limpa os anteriores
+func saveThrottleStats(stats []HostStat) {
+	encoded, err := json.Marshal(ternary.Ternary(stats == nil, []HostStat{}, stats))
+	if err != nil {
+		return
+	}
+	if err := db.SetJobThrottle(config.Job, encoded); err != nil {
+		log.Logger.Error("error saving throttle stats", zap.String("Job", config.Job), zap.Error(err))
+	}
+}
@@ -0,0 +1,23 @@
+package data
+
+import "time"
+
+const (
+	JobRunning  = "running"
+	JobPaused   = "paused"
+	JobFinished = "finished"
+	// JobExhausted o orçamento do crawl acabou; a fila é mantida para ser retomada com um orçamento maior
+	JobExhausted = "exhausted"
+)
+
+// Job crawl nomeado. Config é o snapshot da configuração (JSON), usado ao retomar o job.
+type Job struct {
+	ID     string `json:"id" bson:"id" db:"id"`
+	Status string `json:"status" bson:"status" db:"status"`
+	Config []byte `json:"config" bson:"config" db:"config"`
+	Pages  int64  `json:"pages" bson:"pages" db:"pages"`
+	// Throttle hosts desacelerados pelo controle de taxa na execução (JSON)
+	Throttle  []byte    `json:"throttle" bson:"throttle" db:"throttle"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at" db:"updated_at"`
+}
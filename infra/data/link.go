@@ -0,0 +1,31 @@
+package data
+
+const (
+	LinkTypePage     = "page"
+	LinkTypeResource = "resource"
+)
+
+// Link aresta do grafo de links entre páginas
+type Link struct {
+	Source   string `json:"source" bson:"source" db:"source"`
+	Target   string `json:"target" bson:"target" db:"target"`
+	Anchor   string `json:"anchor" bson:"anchor" db:"anchor"`
+	Rel      string `json:"rel" bson:"rel" db:"rel"`
+	NoFollow bool   `json:"nofollow" bson:"nofollow" db:"nofollow"`
+	Element  string `json:"element" bson:"element" db:"element"`
+	Attr     string `json:"attr" bson:"attr" db:"attr"`
+	// Type LinkTypePage para links seguidos pelo crawler, LinkTypeResource para recursos apenas registrados
+	Type string `json:"type" bson:"type" db:"type"`
+	// Position ordem do link no documento, começando em 0
+	Position int `json:"position" bson:"position" db:"position"`
+	// Job crawl que registrou a aresta
+	Job string `json:"job" bson:"job" db:"job"`
+}
+
+// PageScore pontuações de ranking calculadas sobre o grafo de links
+type PageScore struct {
+	Url       string  `json:"url" bson:"url" db:"url"`
+	PageRank  float64 `json:"pagerank" bson:"pagerank" db:"pagerank"`
+	Hub       float64 `json:"hub" bson:"hub" db:"hub"`
+	Authority float64 `json:"authority" bson:"authority" db:"authority"`
+}
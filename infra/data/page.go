@@ -3,14 +3,54 @@ package data
 import "time"
 
 type Page struct {
-	Url         string         `json:"url" bson:"url" db:"url"`
-	Links       []string       `json:"links" bson:"links" db:"links"`
-	Title       string         `json:"title" bson:"title" db:"title"`
-	Description string         `json:"description" bson:"description" db:"description"`
-	Meta        *MetaData      `json:"meta" bson:"meta" db:"meta"`
-	Visited     bool           `json:"visited" bson:"visited" db:"visited"`
-	Timestamp   time.Time      `json:"timestamp" bson:"timestamp" db:"timestamp"`
-	Words       map[string]int `json:"words" bson:"words" db:"words"`
+	Url              string         `json:"url" bson:"url" db:"url"`
+	Links            []string       `json:"links" bson:"links" db:"links"`
+	Title            string         `json:"title" bson:"title" db:"title"`
+	Description      string         `json:"description" bson:"description" db:"description"`
+	Meta             *MetaData      `json:"meta" bson:"meta" db:"meta"`
+	Visited          bool           `json:"visited" bson:"visited" db:"visited"`
+	Timestamp        time.Time      `json:"timestamp" bson:"timestamp" db:"timestamp"`
+	Words            map[string]int `json:"words" bson:"words" db:"words"`
+	Language         string         `json:"language" bson:"language" db:"language"`
+	Content          string         `json:"content" bson:"content" db:"content"`
+	Depth            int            `json:"depth" bson:"depth" db:"depth"`
+	WarcRecordID     string         `json:"warc_record_id" bson:"warc_record_id" db:"warc_record_id"`
+	HiddenServices   []string       `json:"hidden_services" bson:"hidden_services" db:"hidden_services"`
+	Redirects        []Redirect     `json:"redirects" bson:"redirects" db:"redirects"`
+	ETag             string         `json:"etag" bson:"etag" db:"etag"`
+	LastModified     string         `json:"last_modified" bson:"last_modified" db:"last_modified"`
+	ContentHash      string         `json:"content_hash" bson:"content_hash" db:"content_hash"`
+	LastChecked      time.Time      `json:"last_checked" bson:"last_checked" db:"last_checked"`
+	Truncated        bool           `json:"truncated" bson:"truncated" db:"truncated"`
+	ContentEncoding  string         `json:"content_encoding" bson:"content_encoding" db:"content_encoding"`
+	CompressedSize   int64          `json:"compressed_size" bson:"compressed_size" db:"compressed_size"`
+	UncompressedSize int64          `json:"uncompressed_size" bson:"uncompressed_size" db:"uncompressed_size"`
+	Seed             string         `json:"seed" bson:"seed" db:"seed"`
+	Job              string         `json:"job" bson:"job" db:"job"`
+}
+
+// FetchState validadores da última busca de uma URL, usados em requisições condicionais
+type FetchState struct {
+	ETag         string    `json:"etag"`
+	LastModified string    `json:"last_modified"`
+	ContentHash  string    `json:"content_hash"`
+	LastChecked  time.Time `json:"last_checked"`
+	LastChanged  time.Time `json:"last_changed"`
+	// Interval intervalo adaptativo até a próxima visita, NextVisit horário agendado
+	Interval  time.Duration `json:"interval"`
+	NextVisit time.Time     `json:"next_visit"`
+	Depth     int           `json:"depth"`
+	// RetryAt revisita provisória agendada ao retirar a página do agendamento, removida quando a busca termina;
+	// Failures revisitas seguidas que não terminaram, para o recuo
+	RetryAt  time.Time `json:"retry_at"`
+	Failures int       `json:"failures"`
+}
+
+// Redirect passo da cadeia de redirecionamentos até a URL final da página
+type Redirect struct {
+	Url      string `json:"url" bson:"url"`
+	Status   int    `json:"status" bson:"status"`
+	Location string `json:"location" bson:"location"`
 }
 type MetaData struct {
 	OG       map[string]string `json:"og" bson:"og"`
@@ -31,3 +71,10 @@ type PageSearchWithFrequency struct {
 	PageSearch
 	Frequency int `json:"frequency" bson:"frequency"`
 }
+
+// PageSearchWithRank resultado de uma busca full-text, com relevância e trecho destacado
+type PageSearchWithRank struct {
+	PageSearch
+	Rank     float64 `json:"rank" bson:"rank"`
+	Headline string  `json:"headline" bson:"headline"`
+}
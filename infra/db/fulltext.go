@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"sort"
+	"strings"
+)
+
+// textSearchConfig retorna a configuração de busca textual do Postgres para o idioma informado
+func textSearchConfig(lang string) string {
+	if cfg, ok := config.TextSearchConfigs[lang]; ok {
+		return cfg
+	}
+	return config.DefaultTextSearchConfig
+}
+
+// updateSearchVector recalcula a coluna search da página, com pesos: título A, descrição B, conteúdo C
+func updateSearchVector(url, job, lang string) error {
+	query := `
+		UPDATE pages
+		SET search = setweight(to_tsvector($2::regconfig, coalesce(title, '')), 'A') ||
+		             setweight(to_tsvector($2::regconfig, coalesce(description, '')), 'B') ||
+		             setweight(to_tsvector($2::regconfig, coalesce(content, '')), 'C')
+		WHERE url = $1 AND job = $3;
+	`
+	_, err := sess.SQL().Exec(query, url, textSearchConfig(lang), job)
+	if err != nil {
+		return fmt.Errorf("error updating search vector: %w", err)
+	}
+	return nil
+}
+
+// languageConfigExpr expressão SQL que converte a coluna language na configuração de busca textual
+func languageConfigExpr() string {
+	langs := make([]string, 0, len(config.TextSearchConfigs))
+	for lang := range config.TextSearchConfigs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	var expr strings.Builder
+	expr.WriteString("(CASE language")
+	for _, lang := range langs {
+		expr.WriteString(fmt.Sprintf(" WHEN '%s' THEN '%s'", lang, config.TextSearchConfigs[lang]))
+	}
+	expr.WriteString(fmt.Sprintf(" ELSE '%s' END)::regconfig", config.DefaultTextSearchConfig))
+	return expr.String()
+}
+
+// anyLanguageQuery expressão SQL com a união das consultas ($1) em todas as configurações de busca textual.
+// Ela é constante na consulta, então o filtro search @@ usa o índice GIN; cada página é conferida depois
+// com a configuração do seu idioma.
+func anyLanguageQuery() string {
+	seen := map[string]bool{config.DefaultTextSearchConfig: true}
+	cfgs := []string{config.DefaultTextSearchConfig}
+	for _, cfg := range config.TextSearchConfigs {
+		if !seen[cfg] {
+			seen[cfg] = true
+			cfgs = append(cfgs, cfg)
+		}
+	}
+	sort.Strings(cfgs)
+
+	queries := make([]string, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		queries = append(queries, fmt.Sprintf("websearch_to_tsquery('%s', $1)", cfg))
+	}
+	return "(" + strings.Join(queries, " || ") + ")"
+}
+
+// SearchFullText pesquisa páginas do job atual usando a busca textual do Postgres.
+// searchTerm aceita a sintaxe de websearch_to_tsquery ("frase exata", -excluir, or).
+// Se lang for informado, apenas páginas nesse idioma são consideradas,
+// caso contrário a consulta usa a configuração do idioma de cada página.
+// A relevância textual é ponderada pelo PageRank normalizado da página (ver comando pagerank).
+// limit <= 0 retorna todos os resultados.
+func SearchFullText(ctx context.Context, searchTerm, lang string, limit int) ([]data.PageSearchWithRank, error) {
+	args := []interface{}{searchTerm, config.Job}
+	tsConfig := languageConfigExpr()
+	filter := "WHERE job = $2 AND search @@ " + anyLanguageQuery()
+	if lang != "" {
+		args = append(args, textSearchConfig(lang), lang)
+		tsConfig = "$3::regconfig"
+		filter = "WHERE job = $2 AND search @@ websearch_to_tsquery($3::regconfig, $1) AND language = $4"
+	}
+	limitClause := ""
+	if limit > 0 {
+		limitClause = fmt.Sprintf("LIMIT %d", limit)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT url, title,
+		       ts_rank_cd(search, query) * (1 + coalesce(pagerank, 0)) AS rank,
+		       ts_headline(cfg, coalesce(description, '') || ' ' || coalesce(content, ''), query,
+		                   'MaxFragments=2, MaxWords=30, MinWords=10') AS headline
+		FROM (SELECT url, title, description, content, search, pagerank, %s AS cfg FROM pages %s) AS p,
+		     LATERAL websearch_to_tsquery(p.cfg, $1) AS query
+		WHERE search @@ query
+		ORDER BY rank DESC
+		%s;
+	`, tsConfig, filter, limitClause)
+
+	rows, err := sess.SQL().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pages []data.PageSearchWithRank
+	for rows.Next() {
+		var page data.PageSearchWithRank
+		if err := rows.Scan(&page.Url, &page.Title, &page.Rank, &page.Headline); err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+
+	return pages, rows.Err()
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"github.com/upper/db/v4"
+)
+
+// WriteLinks substitui as arestas de saída de uma página no grafo de links do job atual
+func WriteLinks(source string, links []data.Link) error {
+	return sess.Tx(func(tx db.Session) error {
+		err := tx.Collection("edges").Find(db.Cond{"source": source, "job": config.Job}).Delete()
+		if err != nil {
+			return fmt.Errorf("error deleting edges: %w", err)
+		}
+		for _, link := range links {
+			link.Job = config.Job
+			if _, err := tx.Collection("edges").Insert(link); err != nil {
+				return fmt.Errorf("error inserting edge: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// AllLinks recupera todas as arestas do grafo de links do job atual
+func AllLinks() ([]data.Link, error) {
+	var links []data.Link
+	err := sess.Collection("edges").Find(db.Cond{"job": config.Job}).OrderBy("source", "position").All(&links)
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// WriteScores grava as pontuações de ranking calculadas nas páginas correspondentes
+func WriteScores(scores []data.PageScore) error {
+	return sess.Tx(func(tx db.Session) error {
+		for _, score := range scores {
+			_, err := tx.SQL().Exec(
+				`UPDATE pages SET pagerank = $2, hub = $3, authority = $4 WHERE url = $1 AND job = $5;`,
+				score.Url, score.PageRank, score.Hub, score.Authority, config.Job,
+			)
+			if err != nil {
+				return fmt.Errorf("error updating scores: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GraphFilter filtros para leitura das páginas usadas na exportação do grafo
+type GraphFilter struct {
+	// MaxDepth profundidade máxima das páginas, negativo para ignorar
+	MaxDepth int
+}
+
+// ReadGraphPages recupera url, título, links e profundidade das páginas visitadas
+func ReadGraphPages(filter GraphFilter) ([]data.Page, error) {
+	cond := db.And(db.Cond{"visited": true, "job": config.Job})
+	if filter.MaxDepth >= 0 {
+		// Páginas sem profundidade gravada (NULL) contam como profundidade 0
+		cond = cond.And(db.Raw("COALESCE(depth, 0) <= ?", filter.MaxDepth))
+	}
+	var pages []data.Page
+	err := sess.Collection("pages").Find(cond).
+		Select("url", "title", "links", "depth").
+		OrderBy("url").
+		All(&pages)
+	if err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"github.com/upper/db/v4"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+// SaveJob cria ou atualiza o job com o status e o snapshot da configuração
+func SaveJob(id, status string, snapshot []byte) error {
+	_, err := sess.SQL().Exec(`
+		INSERT INTO jobs (id, status, config, created_at, updated_at)
+		VALUES ($1, $2, $3::jsonb, now(), now())
+		ON CONFLICT (id) DO UPDATE SET status = $2, config = $3::jsonb, updated_at = now();
+	`, id, status, string(snapshot))
+	if err != nil {
+		return fmt.Errorf("error saving job: %w", err)
+	}
+	return nil
+}
+
+// SetJobStatus altera o status do job
+func SetJobStatus(id, status string) error {
+	res, err := sess.SQL().Exec(`UPDATE jobs SET status = $2, updated_at = now() WHERE id = $1;`, id, status)
+	if err != nil {
+		return fmt.Errorf("error updating job: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return nil
+}
+
+// ReadJob recupera o job com o snapshot da configuração
+func ReadJob(id string) (*data.Job, error) {
+	var job data.Job
+	row, err := sess.SQL().QueryRow(`SELECT id, status, config, created_at, updated_at FROM jobs WHERE id = $1;`, id)
+	if err != nil {
+		return nil, err
+	}
+	err = row.Scan(&job.ID, &job.Status, &job.Config, &job.CreatedAt, &job.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SetJobThrottle grava os hosts desacelerados pelo controle de taxa (JSON)
+func SetJobThrottle(id string, stats []byte) error {
+	_, err := sess.SQL().Exec(`UPDATE jobs SET throttle = $2::jsonb, updated_at = now() WHERE id = $1;`, id, string(stats))
+	if err != nil {
+		return fmt.Errorf("error updating job: %w", err)
+	}
+	return nil
+}
+
+// ListJobs lista os jobs com o número de páginas gravadas e os hosts desacelerados, sem o snapshot da configuração
+func ListJobs() ([]data.Job, error) {
+	rows, err := sess.SQL().Query(`
+		SELECT j.id, j.status, coalesce(p.pages, 0), coalesce(j.throttle, '[]'::jsonb), j.created_at, j.updated_at
+		FROM jobs j
+		LEFT JOIN (SELECT job, count(*) AS pages FROM pages GROUP BY job) p ON p.job = j.id
+		ORDER BY j.created_at;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []data.Job
+	for rows.Next() {
+		var job data.Job
+		if err := rows.Scan(&job.ID, &job.Status, &job.Pages, &job.Throttle, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// DeleteJob apaga o job com suas páginas e arestas
+func DeleteJob(id string) error {
+	return sess.Tx(func(tx db.Session) error {
+		for _, query := range []string{
+			`DELETE FROM edges WHERE job = $1;`,
+			`DELETE FROM pages WHERE job = $1;`,
+			`DELETE FROM jobs WHERE id = $1;`,
+		} {
+			if _, err := tx.SQL().Exec(query, id); err != nil {
+				return fmt.Errorf("error deleting job: %w", err)
+			}
+		}
+		return nil
+	})
+}
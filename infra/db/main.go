@@ -3,10 +3,14 @@ package db
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/gabrielmoura/WebCrawler/config"
 	"github.com/gabrielmoura/WebCrawler/infra/data"
 	"github.com/upper/db/v4"
 	"github.com/upper/db/v4/adapter/postgresql"
+	"reflect"
+	"strings"
+	"time"
 )
 
 var sess db.Session
@@ -25,16 +29,42 @@ func InitDB() error {
 	return nil
 }
 
-// WritePage insere uma nova página no banco de dados
+// pageUpsertSet cláusula SET do upsert de páginas: todas as colunas de data.Page, exceto a chave (job, url)
+var pageUpsertSet = func() string {
+	t := reflect.TypeOf(data.Page{})
+	set := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		column := strings.Split(t.Field(i).Tag.Get("db"), ",")[0]
+		if column == "" || column == "-" || column == "url" || column == "job" {
+			continue
+		}
+		set = append(set, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+	}
+	return strings.Join(set, ", ")
+}()
+
+// WritePage insere uma nova página no banco de dados, ou a atualiza se a URL já existir no job,
+// em um único INSERT ... ON CONFLICT (sem corrida entre a verificação e a escrita)
 func WritePage(page *data.Page) error {
-	_, err := sess.Collection("pages").Insert(page)
+	_, err := sess.SQL().InsertInto("pages").Values(page).Amend(func(query string) string {
+		return strings.TrimSuffix(strings.TrimSpace(query), ";") + " ON CONFLICT (job, url) DO UPDATE SET " + pageUpsertSet
+	}).Exec()
+	if err != nil {
+		return err
+	}
+	return updateSearchVector(page.Url, page.Job, page.Language)
+}
+
+// TouchPage atualiza apenas o horário da última verificação de uma página sem alteração
+func TouchPage(url string, checked time.Time) error {
+	_, err := sess.SQL().Exec(`UPDATE pages SET last_checked = $2 WHERE url = $1 AND job = $3;`, url, checked, config.Job)
 	return err
 }
 
-// ReadPage recupera uma página do banco de dados por URL
+// ReadPage recupera uma página do job atual por URL
 func ReadPage(url string) (*data.Page, error) {
 	var page data.Page
-	err := sess.Collection("pages").Find(db.Cond{"url": url}).One(&page)
+	err := sess.Collection("pages").Find(db.Cond{"url": url, "job": config.Job}).One(&page)
 	if err != nil {
 		if errors.Is(err, db.ErrNoMoreRows) {
 			return nil, nil
@@ -44,19 +74,20 @@ func ReadPage(url string) (*data.Page, error) {
 	return &page, nil
 }
 
-// IsVisited verifica se uma URL foi visitada
+// IsVisited verifica se uma URL foi visitada no job atual
 func IsVisited(url string) bool {
 	count, err := sess.Collection("pages").Find(db.And(
 		db.Cond{"url": url},
+		db.Cond{"job": config.Job},
 		db.Cond{"visited": true},
 	)).Count()
 	return err == nil && count > 0
 }
 
-// AllVisited recupera todos os URLs visitados
+// AllVisited recupera todos os URLs visitados no job atual
 func AllVisited() ([]string, error) {
 	var pages []data.Page
-	err := sess.Collection("pages").Find(db.Cond{"visited": true}).All(&pages)
+	err := sess.Collection("pages").Find(db.Cond{"visited": true, "job": config.Job}).All(&pages)
 	if err != nil {
 		return nil, err
 	}
@@ -69,26 +100,29 @@ func AllVisited() ([]string, error) {
 	return urls, nil
 }
 
-// SearchByTitleOrDescription pesquisa páginas por título ou descrição
+// SearchByTitleOrDescription pesquisa páginas do job atual por título ou descrição
 func SearchByTitleOrDescription(ctx context.Context, searchTerm string) ([]data.PageSearch, error) {
 	var pages []data.PageSearch
-	err := sess.WithContext(ctx).Collection("pages").Find(db.Or(
-		db.Cond{"title": db.Cond{"$ilike": "%" + searchTerm + "%"}},
-		db.Cond{"description": db.Cond{"$ilike": "%" + searchTerm + "%"}},
+	err := sess.WithContext(ctx).Collection("pages").Find(db.And(
+		db.Cond{"job": config.Job},
+		db.Or(
+			db.Cond{"title": db.Cond{"$ilike": "%" + searchTerm + "%"}},
+			db.Cond{"description": db.Cond{"$ilike": "%" + searchTerm + "%"}},
+		),
 	)).All(&pages)
 	return pages, err
 }
 
-// SearchByContent pesquisa páginas por conteúdo e ordena por frequência
+// SearchByContent pesquisa páginas do job atual por conteúdo e ordena por frequência
 func SearchByContent(ctx context.Context, searchTerm string) ([]data.PageSearchWithFrequency, error) {
 	var pages []data.PageSearchWithFrequency
 	query := `
-		SELECT url, title, (words->>$2)::int AS frequency
-		FROM $1
-		WHERE words ? $2
+		SELECT url, title, (words->>$1)::int AS frequency
+		FROM pages
+		WHERE job = $2 AND words ? $1
 		ORDER BY frequency DESC NULLS LAST;
 	`
-	rows, err := sess.SQL().QueryContext(ctx, query, "pages", searchTerm)
+	rows, err := sess.SQL().QueryContext(ctx, query, searchTerm, config.Job)
 	if err != nil {
 		return nil, err
 	}
@@ -105,36 +139,15 @@ func SearchByContent(ctx context.Context, searchTerm string) ([]data.PageSearchW
 	return pages, rows.Err()
 }
 
-// Search pesquisa páginas por título, descrição ou conteúdo
+// Search pesquisa páginas do job atual por título, descrição ou conteúdo, ordenadas por relevância
 func Search(ctx context.Context, searchTerm string) ([]data.PageSearch, error) {
-	var pages []data.PageSearch
-	query := `
-		SELECT DISTINCT url, title
-		FROM (
-			SELECT url, title
-			FROM $1
-			WHERE (words->>$2)::int IS NOT NULL
-			UNION
-			SELECT url, title
-			FROM $1
-			WHERE title ILIKE '%' || $2 || '%'
-			OR description ILIKE '%' || $2 || '%'
-		) AS combined_results
-		ORDER BY url;
-	`
-	rows, err := sess.SQL().QueryContext(ctx, query, "pages", searchTerm)
+	ranked, err := SearchFullText(ctx, searchTerm, "", 0)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var page data.PageSearch
-		if err := rows.Scan(&page.Url, &page.Title); err != nil {
-			return nil, err
-		}
-		pages = append(pages, page)
+	pages := make([]data.PageSearch, len(ranked))
+	for i, page := range ranked {
+		pages[i] = page.PageSearch
 	}
-
-	return pages, rows.Err()
+	return pages, nil
 }
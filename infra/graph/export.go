@@ -0,0 +1,371 @@
+package graph
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrUnknownFormat = errors.New("unknown graph format")
+
+// now data de modificação gravada no GEXF, substituída nos testes
+var now = time.Now
+
+const (
+	LevelHost = "host"
+	LevelPage = "page"
+)
+
+// ExportFilter restringe os nós exportados por domínio ou TLD. Listas vazias não filtram.
+type ExportFilter struct {
+	Domains []string
+	Tlds    []string
+}
+
+// ExportNode nó do grafo exportado
+type ExportNode struct {
+	ID        string
+	Title     string
+	PageCount int
+	InDegree  int
+	OutDegree int
+}
+
+// ExportEdge aresta do grafo exportado; Weight conta os links entre os nós
+type ExportEdge struct {
+	Source string
+	Target string
+	Weight int
+}
+
+// Export grafo pronto para ser escrito em GraphML, GEXF ou DOT
+type Export struct {
+	Nodes []*ExportNode
+	Edges []*ExportEdge
+}
+
+// hasHostSuffix verifica se host é igual ao sufixo ou um subdomínio dele
+func hasHostSuffix(host, suffix string) bool {
+	suffix = strings.TrimPrefix(strings.ToLower(suffix), ".")
+	return host == suffix || strings.HasSuffix(host, "."+suffix)
+}
+
+func (f ExportFilter) match(host string) bool {
+	if len(f.Domains) > 0 {
+		ok := false
+		for _, domain := range f.Domains {
+			if hasHostSuffix(host, domain) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(f.Tlds) > 0 {
+		for _, tld := range f.Tlds {
+			if hasHostSuffix(host, tld) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// BuildExport monta o grafo em nível de host ou de página a partir das páginas armazenadas.
+// Links para páginas ainda não visitadas geram nós com PageCount zero.
+func BuildExport(pages []data.Page, level string, filter ExportFilter) (*Export, error) {
+	if level != LevelHost && level != LevelPage {
+		return nil, fmt.Errorf("invalid graph level %q", level)
+	}
+
+	nodes := make(map[string]*ExportNode)
+	edges := make(map[[2]string]*ExportEdge)
+
+	// nodeID retorna o identificador do nó para a URL, ou "" se ela for filtrada
+	nodeID := func(link string) string {
+		u, err := url.Parse(link)
+		if err != nil || u.Hostname() == "" {
+			return ""
+		}
+		host := strings.ToLower(u.Hostname())
+		if !filter.match(host) {
+			return ""
+		}
+		if level == LevelHost {
+			return host
+		}
+		return link
+	}
+	getNode := func(id string) *ExportNode {
+		node, ok := nodes[id]
+		if !ok {
+			node = &ExportNode{ID: id}
+			nodes[id] = node
+		}
+		return node
+	}
+
+	for _, page := range pages {
+		id := nodeID(page.Url)
+		if id == "" {
+			continue
+		}
+		node := getNode(id)
+		node.PageCount++
+		if node.Title == "" || (level == LevelHost && isRootPage(page.Url)) {
+			node.Title = strings.TrimSpace(page.Title)
+		}
+
+		for _, link := range page.Links {
+			target := nodeID(link)
+			if target == "" || target == id {
+				continue
+			}
+			getNode(target)
+			key := [2]string{id, target}
+			edge, ok := edges[key]
+			if !ok {
+				edge = &ExportEdge{Source: id, Target: target}
+				edges[key] = edge
+			}
+			edge.Weight++
+		}
+	}
+
+	export := &Export{}
+	for _, edge := range edges {
+		nodes[edge.Source].OutDegree++
+		nodes[edge.Target].InDegree++
+		export.Edges = append(export.Edges, edge)
+	}
+	for _, node := range nodes {
+		export.Nodes = append(export.Nodes, node)
+	}
+	sort.Slice(export.Nodes, func(i, j int) bool { return export.Nodes[i].ID < export.Nodes[j].ID })
+	sort.Slice(export.Edges, func(i, j int) bool {
+		if export.Edges[i].Source != export.Edges[j].Source {
+			return export.Edges[i].Source < export.Edges[j].Source
+		}
+		return export.Edges[i].Target < export.Edges[j].Target
+	})
+	return export, nil
+}
+
+// isRootPage verifica se a URL é a página inicial do host
+func isRootPage(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == ""
+}
+
+// Write escreve o grafo no formato informado: graphml, gexf ou dot
+func (e *Export) Write(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "graphml":
+		return e.WriteGraphML(w)
+	case "gexf":
+		return e.WriteGEXF(w)
+	case "dot", "gv":
+		return e.WriteDOT(w)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+type graphmlDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+type graphmlKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+type graphmlGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+type graphmlEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphmlData `xml:"data"`
+}
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML escreve o grafo em GraphML
+func (e *Export) WriteGraphML(w io.Writer) error {
+	doc := graphmlDoc{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphmlKey{
+			{ID: "title", For: "node", AttrName: "title", AttrType: "string"},
+			{ID: "pages", For: "node", AttrName: "page_count", AttrType: "int"},
+			{ID: "indegree", For: "node", AttrName: "in_degree", AttrType: "int"},
+			{ID: "outdegree", For: "node", AttrName: "out_degree", AttrType: "int"},
+			{ID: "weight", For: "edge", AttrName: "weight", AttrType: "int"},
+		},
+		Graph: graphmlGraph{ID: "crawl", EdgeDefault: "directed"},
+	}
+	for _, node := range e.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphmlNode{
+			ID: node.ID,
+			Data: []graphmlData{
+				{Key: "title", Value: node.Title},
+				{Key: "pages", Value: fmt.Sprint(node.PageCount)},
+				{Key: "indegree", Value: fmt.Sprint(node.InDegree)},
+				{Key: "outdegree", Value: fmt.Sprint(node.OutDegree)},
+			},
+		})
+	}
+	for _, edge := range e.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphmlEdge{
+			Source: edge.Source,
+			Target: edge.Target,
+			Data:   []graphmlData{{Key: "weight", Value: fmt.Sprint(edge.Weight)}},
+		})
+	}
+	return writeXML(w, doc)
+}
+
+type gexfDoc struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Meta    gexfMeta  `xml:"meta"`
+	Graph   gexfGraph `xml:"graph"`
+}
+type gexfMeta struct {
+	LastModified string `xml:"lastmodifieddate,attr"`
+	Creator      string `xml:"creator"`
+}
+type gexfGraph struct {
+	DefaultEdgeType string         `xml:"defaultedgetype,attr"`
+	Attributes      gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode     `xml:"nodes>node"`
+	Edges           []gexfEdge     `xml:"edges>edge"`
+}
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+type gexfEdge struct {
+	ID     int    `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+	Weight int    `xml:"weight,attr"`
+}
+
+// WriteGEXF escreve o grafo em GEXF 1.3, formato nativo do Gephi
+func (e *Export) WriteGEXF(w io.Writer) error {
+	doc := gexfDoc{
+		Xmlns:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Meta: gexfMeta{
+			LastModified: now().Format("2006-01-02"),
+			Creator:      "WebCrawler",
+		},
+		Graph: gexfGraph{
+			DefaultEdgeType: "directed",
+			Attributes: gexfAttributes{
+				Class: "node",
+				Attributes: []gexfAttribute{
+					{ID: "title", Title: "title", Type: "string"},
+					{ID: "pages", Title: "page_count", Type: "integer"},
+					{ID: "indegree", Title: "in_degree", Type: "integer"},
+					{ID: "outdegree", Title: "out_degree", Type: "integer"},
+				},
+			},
+		},
+	}
+	for _, node := range e.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{
+			ID:    node.ID,
+			Label: node.ID,
+			AttValues: []gexfAttValue{
+				{For: "title", Value: node.Title},
+				{For: "pages", Value: fmt.Sprint(node.PageCount)},
+				{For: "indegree", Value: fmt.Sprint(node.InDegree)},
+				{For: "outdegree", Value: fmt.Sprint(node.OutDegree)},
+			},
+		})
+	}
+	for i, edge := range e.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+			ID:     i,
+			Source: edge.Source,
+			Target: edge.Target,
+			Weight: edge.Weight,
+		})
+	}
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// dotQuote escapa uma string para uso como identificador entre aspas no Graphviz
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", ` `)
+	return `"` + s + `"`
+}
+
+// WriteDOT escreve o grafo no formato DOT do Graphviz
+func (e *Export) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph crawl {\n")
+	for _, node := range e.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, title=%s, page_count=%d, in_degree=%d, out_degree=%d];\n",
+			dotQuote(node.ID), dotQuote(node.ID), dotQuote(node.Title), node.PageCount, node.InDegree, node.OutDegree)
+	}
+	for _, edge := range e.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [weight=%d];\n", dotQuote(edge.Source), dotQuote(edge.Target), edge.Weight)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
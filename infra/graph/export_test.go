@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// escapedExport grafo com URL e título que exigem escape em XML e DOT (&, " e <)
+func escapedExport(t *testing.T) *Export {
+	t.Helper()
+	pages := []data.Page{
+		{Url: `http://a.example/?q=1&b="x"<y`, Title: `A & "B" <C>`, Links: []string{"http://b.example/"}},
+		{Url: "http://b.example/", Title: "B", Links: []string{`http://a.example/?q=1&b="x"<y`, "http://c.example/"}},
+	}
+	export, err := BuildExport(pages, LevelPage, ExportFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return export
+}
+
+func TestExportGolden(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	for _, format := range []string{"graphml", "gexf", "dot"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := escapedExport(t).Write(&buf, format); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "escaped."+format)
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("%s output differs from %s:\n%s", format, golden, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	if err := escapedExport(t).Write(&bytes.Buffer{}, "csv"); err == nil {
+		t.Error("csv format accepted")
+	}
+}
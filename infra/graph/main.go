@@ -0,0 +1,157 @@
+package graph
+
+import (
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+	"math"
+)
+
+// Graph grafo direcionado de links entre páginas
+type Graph struct {
+	Nodes []string
+	index map[string]int
+	out   [][]int
+	in    [][]int
+}
+
+// NewGraph cria um grafo a partir das arestas, ignorando recursos, links nofollow e auto-referências.
+// Arestas repetidas entre as mesmas páginas contam uma única vez.
+func NewGraph(links []data.Link) *Graph {
+	g := &Graph{index: make(map[string]int)}
+	seen := make(map[[2]int]struct{})
+	for _, link := range links {
+		if link.Type == data.LinkTypeResource || link.NoFollow || link.Source == link.Target {
+			continue
+		}
+		from, to := g.node(link.Source), g.node(link.Target)
+		if _, ok := seen[[2]int{from, to}]; ok {
+			continue
+		}
+		seen[[2]int{from, to}] = struct{}{}
+		g.out[from] = append(g.out[from], to)
+		g.in[to] = append(g.in[to], from)
+	}
+	return g
+}
+
+// node retorna o índice do nó, criando-o se necessário
+func (g *Graph) node(url string) int {
+	if i, ok := g.index[url]; ok {
+		return i
+	}
+	i := len(g.Nodes)
+	g.index[url] = i
+	g.Nodes = append(g.Nodes, url)
+	g.out = append(g.out, nil)
+	g.in = append(g.in, nil)
+	return i
+}
+
+// PageRank calcula o PageRank iterativamente até convergir (diferença L1 menor que tolerance)
+// ou atingir maxIterations. Páginas sem links de saída distribuem seu rank igualmente.
+func (g *Graph) PageRank(damping float64, maxIterations int, tolerance float64) map[string]float64 {
+	n := len(g.Nodes)
+	if n == 0 {
+		return map[string]float64{}
+	}
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+
+	next := make([]float64, n)
+	for iter := 0; iter < maxIterations; iter++ {
+		var dangling float64
+		for i := range g.Nodes {
+			if len(g.out[i]) == 0 {
+				dangling += rank[i]
+			}
+		}
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i := range g.Nodes {
+			if len(g.out[i]) == 0 {
+				continue
+			}
+			share := damping * rank[i] / float64(len(g.out[i]))
+			for _, j := range g.out[i] {
+				next[j] += share
+			}
+		}
+
+		var diff float64
+		for i := range rank {
+			diff += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if diff < tolerance {
+			break
+		}
+	}
+
+	return g.toMap(rank)
+}
+
+// HITS calcula as pontuações de hub e autoridade (Kleinberg) normalizadas pela norma L2
+func (g *Graph) HITS(maxIterations int, tolerance float64) (hubs, authorities map[string]float64) {
+	n := len(g.Nodes)
+	hub := make([]float64, n)
+	auth := make([]float64, n)
+	for i := range hub {
+		hub[i] = 1
+		auth[i] = 1
+	}
+
+	for iter := 0; iter < maxIterations; iter++ {
+		nextAuth := make([]float64, n)
+		for i := range g.Nodes {
+			for _, j := range g.in[i] {
+				nextAuth[i] += hub[j]
+			}
+		}
+		normalize(nextAuth)
+
+		nextHub := make([]float64, n)
+		for i := range g.Nodes {
+			for _, j := range g.out[i] {
+				nextHub[i] += nextAuth[j]
+			}
+		}
+		normalize(nextHub)
+
+		var diff float64
+		for i := range hub {
+			diff += math.Abs(nextHub[i]-hub[i]) + math.Abs(nextAuth[i]-auth[i])
+		}
+		hub, auth = nextHub, nextAuth
+		if diff < tolerance {
+			break
+		}
+	}
+
+	return g.toMap(hub), g.toMap(auth)
+}
+
+// normalize divide os valores pela norma L2
+func normalize(values []float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v * v
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for i := range values {
+		values[i] /= norm
+	}
+}
+
+func (g *Graph) toMap(values []float64) map[string]float64 {
+	m := make(map[string]float64, len(values))
+	for i, v := range values {
+		m[g.Nodes[i]] = v
+	}
+	return m
+}
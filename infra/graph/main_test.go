@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gabrielmoura/WebCrawler/infra/data"
+)
+
+// edges monta links de página a partir de pares origem, destino
+func edges(pairs ...string) []data.Link {
+	var links []data.Link
+	for i := 0; i+1 < len(pairs); i += 2 {
+		links = append(links, data.Link{Source: pairs[i], Target: pairs[i+1], Type: data.LinkTypePage})
+	}
+	return links
+}
+
+func assertScores(t *testing.T, kind string, got, want map[string]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d nodes %v, want %d", kind, len(got), got, len(want))
+	}
+	for node, score := range want {
+		if math.Abs(got[node]-score) > 1e-6 {
+			t.Errorf("%s[%s] = %.6f, want %.6f", kind, node, got[node], score)
+		}
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	const damping = 0.85
+	tests := []struct {
+		name  string
+		links []data.Link
+		want  map[string]float64
+	}{
+		{name: "empty graph", want: map[string]float64{}},
+		{
+			name:  "cycle",
+			links: edges("a", "b", "b", "c", "c", "a"),
+			want:  map[string]float64{"a": 1.0 / 3, "b": 1.0 / 3, "c": 1.0 / 3},
+		},
+		{
+			// b não tem links de saída e distribui seu rank entre todos: a = 1/(2+d)
+			name:  "dangling node",
+			links: edges("a", "b"),
+			want:  map[string]float64{"a": 1 / (2 + damping), "b": (1 + damping) / (2 + damping)},
+		},
+		{
+			// Arestas repetidas, auto-referências, nofollow e recursos são ignorados
+			name: "ignored edges",
+			links: append(edges("a", "b", "a", "b", "b", "a", "a", "a"),
+				data.Link{Source: "a", Target: "c", NoFollow: true},
+				data.Link{Source: "b", Target: "style.css", Type: data.LinkTypeResource}),
+			want: map[string]float64{"a": 0.5, "b": 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rank := NewGraph(tt.links).PageRank(damping, 1000, 1e-12)
+			assertScores(t, "rank", rank, tt.want)
+			if len(rank) > 0 {
+				var sum float64
+				for _, score := range rank {
+					sum += score
+				}
+				if math.Abs(sum-1) > 1e-9 {
+					t.Errorf("ranks sum to %f, want 1", sum)
+				}
+			}
+		})
+	}
+}
+
+func TestPageRankConverges(t *testing.T) {
+	g := NewGraph(edges("a", "b", "b", "c", "c", "a", "a", "c", "d", "a"))
+	converged := g.PageRank(0.85, 1000, 1e-12)
+	// Mais iterações depois da convergência não alteram o resultado
+	assertScores(t, "rank", g.PageRank(0.85, 5000, 1e-15), converged)
+	// Uma única iteração ainda não converge
+	once := g.PageRank(0.85, 1, 0)
+	if math.Abs(once["a"]-converged["a"]) < 1e-6 {
+		t.Errorf("rank after one iteration %f already equals the converged %f", once["a"], converged["a"])
+	}
+}
+
+func TestHITS(t *testing.T) {
+	tests := []struct {
+		name              string
+		links             []data.Link
+		hubs, authorities map[string]float64
+	}{
+		{name: "empty graph", hubs: map[string]float64{}, authorities: map[string]float64{}},
+		{
+			name:        "cycle",
+			links:       edges("a", "b", "b", "c", "c", "a"),
+			hubs:        map[string]float64{"a": 1 / math.Sqrt(3), "b": 1 / math.Sqrt(3), "c": 1 / math.Sqrt(3)},
+			authorities: map[string]float64{"a": 1 / math.Sqrt(3), "b": 1 / math.Sqrt(3), "c": 1 / math.Sqrt(3)},
+		},
+		{
+			// b é a única autoridade; b e c não apontam para nada (hub zero)
+			name:        "dangling authority",
+			links:       edges("a", "b", "c", "b"),
+			hubs:        map[string]float64{"a": 1 / math.Sqrt2, "b": 0, "c": 1 / math.Sqrt2},
+			authorities: map[string]float64{"a": 0, "b": 1, "c": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hubs, authorities := NewGraph(tt.links).HITS(1000, 1e-12)
+			assertScores(t, "hub", hubs, tt.hubs)
+			assertScores(t, "authority", authorities, tt.authorities)
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gabrielmoura/WebCrawler/config"
+	"github.com/gabrielmoura/go/pkg/ternary"
+	"golang.org/x/net/publicsuffix"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var ErrInvalidRule = errors.New("invalid scope rule")
+
+const (
+	ActionInclude = "include"
+	ActionExclude = "exclude"
+
+	// PresetSameHost hosts das URLs iniciais
+	PresetSameHost = "same-host"
+	// PresetSameDomain mesmo domínio registrável (eTLD+1, pela public suffix list) das URLs iniciais
+	PresetSameDomain = "same-domain"
+)
+
+// Decision resultado da avaliação de uma URL. Rule é o índice da regra que decidiu, -1 sem regra.
+type Decision struct {
+	Allowed bool
+	Rule    int
+	Reason  string
+	// Trace avaliação de cada regra, preenchido apenas por Explain
+	Trace []string
+}
+
+// rule regra com a expressão regular já compilada
+type rule struct {
+	config.ScopeRule
+	regex *regexp.Regexp
+}
+
+var (
+	mu       sync.RWMutex
+	rules    []rule
+	hosts    = make(map[string]bool)
+	domains  = make(map[string]bool)
+	loadOnce sync.Once
+	loadErr  error
+)
+
+// Init compila as regras de escopo da configuração
+func Init() error {
+	loadOnce.Do(func() {
+		loadErr = load(config.Conf.Scope)
+	})
+	return loadErr
+}
+
+func load(cfg *config.Scope) error {
+	if cfg == nil {
+		return nil
+	}
+	compiled := make([]rule, 0, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		action := strings.ToLower(r.Action)
+		if action != ActionInclude && action != ActionExclude {
+			return fmt.Errorf("%w %d: action %q", ErrInvalidRule, i, r.Action)
+		}
+		if r.Preset != "" && r.Preset != PresetSameHost && r.Preset != PresetSameDomain {
+			return fmt.Errorf("%w %d: preset %q", ErrInvalidRule, i, r.Preset)
+		}
+		c := rule{ScopeRule: r}
+		c.Action = action
+		if r.Regex != "" {
+			regex, err := regexp.Compile(r.Regex)
+			if err != nil {
+				return fmt.Errorf("%w %d: %v", ErrInvalidRule, i, err)
+			}
+			c.regex = regex
+		}
+		compiled = append(compiled, c)
+	}
+	mu.Lock()
+	rules = compiled
+	mu.Unlock()
+	return nil
+}
+
+// AddSeed registra uma URL inicial para os presets same-host e same-domain
+func AddSeed(seed string) {
+	link, err := url.Parse(seed)
+	if err != nil || link.Hostname() == "" {
+		return
+	}
+	host := strings.ToLower(link.Hostname())
+	mu.Lock()
+	hosts[host] = true
+	domains[registrableDomain(host)] = true
+	mu.Unlock()
+}
+
+// registrableDomain domínio registrável (eTLD+1) do host; o próprio host para IPs e nomes sem sufixo público
+func registrableDomain(host string) string {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// hostMatches verifica se o host é o domínio ou um de seus subdomínios
+func hostMatches(host, domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// Allowed verifica se a URL, na profundidade informada, está no escopo
+func Allowed(link string, depth int) bool {
+	return evaluate(link, depth, false).Allowed
+}
+
+// Explain avalia a URL registrando o resultado de cada regra
+func Explain(link string, depth int) Decision {
+	return evaluate(link, depth, true)
+}
+
+// evaluate aplica o filtro de TLDs e depois as regras em ordem: a primeira que corresponde decide.
+// Sem correspondência vale DEFAULT (include se vazio).
+func evaluate(link string, depth int, trace bool) Decision {
+	linkUrl, err := url.Parse(link)
+	if err != nil {
+		return Decision{Rule: -1, Reason: fmt.Sprintf("invalid URL: %v", err)}
+	}
+	host := strings.ToLower(linkUrl.Hostname())
+	if tlds := config.Conf.Filter.Tlds; len(tlds) > 0 {
+		matched := false
+		for _, tld := range tlds {
+			if tld != "" && hostMatches(host, tld) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return Decision{Rule: -1, Reason: fmt.Sprintf("host %q not in FILTER.TLDS %v", host, tlds)}
+		}
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	var decision Decision
+	for i, r := range rules {
+		mismatch := r.match(linkUrl, host, depth)
+		if trace {
+			decision.Trace = append(decision.Trace, fmt.Sprintf("rule %d (%s %s): %s", i, r.Action, r.describe(),
+				ternary.Ternary(mismatch == "", "match", mismatch)))
+		}
+		if mismatch == "" {
+			decision.Allowed = r.Action == ActionInclude
+			decision.Rule = i
+			decision.Reason = fmt.Sprintf("rule %d (%s %s)", i, r.Action, r.describe())
+			return decision
+		}
+	}
+	defaultAction := ActionInclude
+	if cfg := config.Conf.Scope; cfg != nil && cfg.Default != "" {
+		defaultAction = strings.ToLower(cfg.Default)
+	}
+	decision.Allowed = defaultAction == ActionInclude
+	decision.Rule = -1
+	decision.Reason = fmt.Sprintf("no rule matched, default %s", defaultAction)
+	return decision
+}
+
+// match verifica todas as condições da regra, retornando a primeira que falhou ou "" se corresponde
+func (r rule) match(link *url.URL, host string, depth int) string {
+	if r.Host != "" && !strings.EqualFold(host, r.Host) {
+		return fmt.Sprintf("host %q is not %q", host, r.Host)
+	}
+	if r.Domain != "" && !hostMatches(host, r.Domain) {
+		return fmt.Sprintf("host %q is not under %q", host, r.Domain)
+	}
+	if r.PathPrefix != "" && !strings.HasPrefix(link.EscapedPath(), r.PathPrefix) {
+		return fmt.Sprintf("path %q does not start with %q", link.EscapedPath(), r.PathPrefix)
+	}
+	if r.regex != nil && !r.regex.MatchString(link.String()) {
+		return fmt.Sprintf("URL does not match %q", r.Regex)
+	}
+	query := link.Query()
+	for _, param := range r.Query {
+		name, value, hasValue := strings.Cut(param, "=")
+		if !query.Has(name) || (hasValue && query.Get(name) != value) {
+			return fmt.Sprintf("query has no %q", param)
+		}
+	}
+	if r.MinDepth != nil && depth < *r.MinDepth {
+		return fmt.Sprintf("depth %d < %d", depth, *r.MinDepth)
+	}
+	if r.MaxDepth != nil && depth > *r.MaxDepth {
+		return fmt.Sprintf("depth %d > %d", depth, *r.MaxDepth)
+	}
+	switch r.Preset {
+	case PresetSameHost:
+		if !hosts[host] {
+			return fmt.Sprintf("host %q is not a seed host", host)
+		}
+	case PresetSameDomain:
+		if !domains[registrableDomain(host)] {
+			return fmt.Sprintf("domain %q is not a seed domain", registrableDomain(host))
+		}
+	}
+	return ""
+}
+
+// describe condições da regra, para as explicações
+func (r rule) describe() string {
+	var parts []string
+	add := func(name, value string) {
+		if value != "" {
+			parts = append(parts, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+	add("preset", r.Preset)
+	add("host", r.Host)
+	add("domain", r.Domain)
+	add("path", r.PathPrefix)
+	add("regex", r.Regex)
+	add("query", strings.Join(r.Query, "&"))
+	if r.MinDepth != nil {
+		add("minDepth", fmt.Sprint(*r.MinDepth))
+	}
+	if r.MaxDepth != nil {
+		add("maxDepth", fmt.Sprint(*r.MaxDepth))
+	}
+	if len(parts) == 0 {
+		return "any"
+	}
+	return strings.Join(parts, " ")
+}
+
+// SameSeed verifica o preset (same-host ou same-domain) em relação a uma única URL inicial. Preset vazio aceita qualquer URL.
+func SameSeed(preset, link, seed string) bool {
+	if preset == "" {
+		return true
+	}
+	linkUrl, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	seedUrl, err := url.Parse(seed)
+	if err != nil {
+		return false
+	}
+	host, seedHost := strings.ToLower(linkUrl.Hostname()), strings.ToLower(seedUrl.Hostname())
+	switch preset {
+	case PresetSameHost:
+		return host == seedHost
+	case PresetSameDomain:
+		return registrableDomain(host) == registrableDomain(seedHost)
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package warc
+
+import (
+	"github.com/gabrielmoura/WebCrawler/config"
+	"net/http"
+)
+
+var writer *Writer
+
+// InitWarc inicializa o Writer global se a gravação WARC estiver habilitada
+func InitWarc() error {
+	if !config.Conf.Warc.Enabled {
+		return nil
+	}
+	w, err := NewWriter(config.Conf.Warc.Dir, config.Conf.Warc.Prefix, config.Conf.Warc.MaxSize)
+	if err != nil {
+		return err
+	}
+	writer = w
+	return nil
+}
+
+// Enabled indica se a gravação WARC está ativa
+func Enabled() bool {
+	return writer != nil
+}
+
+// Archive grava request, response e metadata de uma busca, retornando o WARC-Record-ID do response.
+// truncated é o motivo do WARC-Truncated (ex: TruncatedLength), vazio se o corpo está completo.
+// Retorna "" sem erro quando a gravação WARC está desabilitada.
+func Archive(resp *http.Response, body []byte, truncated string, metadata map[string]string) (string, error) {
+	if writer == nil {
+		return "", nil
+	}
+	records := NewExchange(resp, body, truncated, metadata)
+	if err := writer.WriteRecords(records...); err != nil {
+		return "", err
+	}
+	return records[0].ID(), nil
+}
+
+// CloseWarc fecha o arquivo WARC atual
+func CloseWarc() error {
+	if writer == nil {
+		return nil
+	}
+	return writer.Close()
+}
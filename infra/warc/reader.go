@@ -0,0 +1,69 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// Reader lê registros de um arquivo WARC, comprimido (.warc.gz) ou não
+type Reader struct {
+	r      *bufio.Reader
+	closer []io.Closer
+}
+
+// NewReader cria um Reader sobre r, detectando compressão gzip pelo cabeçalho
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		// gzip.Reader lê membros concatenados em sequência (um por registro)
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &Reader{r: bufio.NewReader(gz), closer: []io.Closer{gz}}, nil
+	}
+	return &Reader{r: br}, nil
+}
+
+// Open abre um arquivo WARC para leitura
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	reader.closer = append(reader.closer, file)
+	return reader, nil
+}
+
+// Next retorna o próximo registro, ou io.EOF ao final do arquivo
+func (r *Reader) Next() (*Record, error) {
+	return ReadRecord(r.r)
+}
+
+// Close fecha o arquivo e o descompressor
+func (r *Reader) Close() error {
+	var err error
+	for _, c := range r.closer {
+		if cErr := c.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+// IsWarcFile verifica pela extensão se o arquivo é um WARC
+func IsWarcFile(path string) bool {
+	return strings.HasSuffix(path, ".warc") || strings.HasSuffix(path, ".warc.gz")
+}
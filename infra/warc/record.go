@@ -0,0 +1,210 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Version = "WARC/1.1"
+
+// Tipos de registro WARC usados pelo crawler
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+	TypeMetadata = "metadata"
+)
+
+// TruncatedLength valor de WARC-Truncated para corpos cortados pelo limite de tamanho
+const TruncatedLength = "length"
+
+// Record registro WARC: cabeçalhos nomeados e bloco de conteúdo
+type Record struct {
+	Header http.Header
+	Block  []byte
+}
+
+// NewRecord cria um registro com os campos obrigatórios preenchidos
+func NewRecord(recordType, targetURI, contentType string, block []byte) *Record {
+	r := &Record{Header: make(http.Header), Block: block}
+	r.Header.Set("WARC-Type", recordType)
+	r.Header.Set("WARC-Record-ID", NewRecordID())
+	r.Header.Set("WARC-Date", time.Now().UTC().Format(time.RFC3339Nano))
+	if targetURI != "" {
+		r.Header.Set("WARC-Target-URI", targetURI)
+	}
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	r.Header.Set("WARC-Block-Digest", Digest(block))
+	return r
+}
+
+// Type retorna o WARC-Type do registro
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// ID retorna o WARC-Record-ID do registro
+func (r *Record) ID() string {
+	return r.Header.Get("WARC-Record-ID")
+}
+
+// TargetURI retorna o WARC-Target-URI do registro
+func (r *Record) TargetURI() string {
+	return r.Header.Get("WARC-Target-URI")
+}
+
+// NewRecordID gera um identificador <urn:uuid:...> com UUID v4
+func NewRecordID() string {
+	var u [16]byte
+	_, _ = rand.Read(u[:])
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// Digest calcula o digest SHA-1 em base32, formato usado em WARC-Block-Digest e WARC-Payload-Digest
+func Digest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// WriteTo serializa o registro no formato WARC (sem compressão)
+func (r *Record) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	buf.WriteString(Version + "\r\n")
+	r.Header.Set("Content-Length", strconv.Itoa(len(r.Block)))
+	// WARC-Type e WARC-Record-ID primeiro para facilitar a leitura humana
+	for _, key := range []string{"WARC-Type", "WARC-Record-ID"} {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, r.Header.Get(key))
+	}
+	keys := make([]string, 0, len(r.Header))
+	for key := range r.Header {
+		if key != "Warc-Type" && key != "Warc-Record-Id" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, v := range r.Header[key] {
+			fmt.Fprintf(&buf, "%s: %s\r\n", warcFieldName(key), v)
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(r.Block)
+	buf.WriteString("\r\n\r\n")
+	return buf.WriteTo(w)
+}
+
+// warcFieldName restaura a grafia dos campos WARC-* alterada pela canonicalização de http.Header
+func warcFieldName(key string) string {
+	if strings.HasPrefix(key, "Warc-") {
+		name := "WARC-" + key[len("Warc-"):]
+		name = strings.Replace(name, "-Id", "-ID", 1)
+		name = strings.Replace(name, "-Uri", "-URI", 1)
+		name = strings.Replace(name, "-Ip-", "-IP-", 1)
+		return name
+	}
+	return key
+}
+
+// ReadRecord lê o próximo registro WARC (não comprimido). Retorna io.EOF quando não há mais registros.
+func ReadRecord(r *bufio.Reader) (*Record, error) {
+	var line string
+	// Ignora linhas em branco entre registros
+	for {
+		l, err := r.ReadString('\n')
+		if err == io.EOF && strings.TrimSpace(l) == "" {
+			return nil, io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimRight(l, "\r\n")
+		if line != "" {
+			break
+		}
+	}
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, fmt.Errorf("invalid WARC version line: %q", line)
+	}
+
+	record := &Record{Header: make(http.Header)}
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("error reading WARC header: %w", err)
+		}
+		l = strings.TrimRight(l, "\r\n")
+		if l == "" {
+			break
+		}
+		key, value, ok := strings.Cut(l, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid WARC header line: %q", l)
+		}
+		record.Header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+
+	length, err := strconv.Atoi(record.Header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WARC Content-Length: %w", err)
+	}
+	record.Block = make([]byte, length)
+	if _, err := io.ReadFull(r, record.Block); err != nil {
+		return nil, fmt.Errorf("error reading WARC block: %w", err)
+	}
+	return record, nil
+}
+
+// Date retorna o WARC-Date do registro
+func (r *Record) Date() time.Time {
+	date, _ := time.Parse(time.RFC3339Nano, r.Header.Get("WARC-Date"))
+	return date
+}
+
+// Fields interpreta um bloco application/warc-fields (registros warcinfo e metadata)
+func (r *Record) Fields() map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(string(r.Block), "\n") {
+		key, value, ok := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if ok {
+			fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+// HTTPResponse reconstrói a resposta HTTP de um registro response, retornando também o corpo
+func (r *Record) HTTPResponse() (*http.Response, []byte, error) {
+	if r.Type() != TypeResponse {
+		return nil, nil, fmt.Errorf("record %s is not a response", r.ID())
+	}
+	req, err := http.NewRequest(http.MethodGet, r.TargetURI(), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid WARC-Target-URI: %w", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(r.Block)), req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing HTTP response: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	// Corpos truncados são aceitos como estão
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, nil, fmt.Errorf("error reading HTTP body: %w", err)
+	}
+	return resp, body, nil
+}
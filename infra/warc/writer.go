@@ -0,0 +1,183 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Writer grava registros WARC em arquivos .warc.gz rotativos.
+// Cada registro é um membro gzip independente, permitindo acesso aleatório por offset.
+type Writer struct {
+	dir     string
+	prefix  string
+	maxSize int64
+
+	mu         sync.Mutex
+	file       *os.File
+	size       int64
+	serial     int
+	warcinfoID string
+}
+
+// NewWriter cria um Writer que rotaciona o arquivo ao ultrapassar maxSize bytes (comprimidos)
+func NewWriter(dir, prefix string, maxSize int64) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating WARC dir: %w", err)
+	}
+	return &Writer{dir: dir, prefix: prefix, maxSize: maxSize}, nil
+}
+
+// WriteRecords grava os registros no mesmo arquivo, na ordem informada
+func (w *Writer) WriteRecords(records ...*Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil || (w.maxSize > 0 && w.size >= w.maxSize) {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	for _, record := range records {
+		record.Header.Set("WARC-Warcinfo-ID", w.warcinfoID)
+		if err := w.write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write grava um registro como um membro gzip
+func (w *Writer) write(record *Record) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := record.WriteTo(gz); err != nil {
+		return fmt.Errorf("error compressing WARC record: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("error compressing WARC record: %w", err)
+	}
+	n, err := w.file.Write(buf.Bytes())
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("error writing WARC record: %w", err)
+	}
+	return nil
+}
+
+// rotate fecha o arquivo atual e abre um novo, iniciado por um registro warcinfo
+func (w *Writer) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("error closing WARC file: %w", err)
+		}
+	}
+	w.serial++
+	name := fmt.Sprintf("%s-%s-%05d.warc.gz", w.prefix, time.Now().UTC().Format("20060102150405"), w.serial)
+	file, err := os.OpenFile(filepath.Join(w.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error creating WARC file: %w", err)
+	}
+	w.file = file
+	w.size = 0
+
+	info := NewRecord(TypeWarcinfo, "", "application/warc-fields", warcFields(map[string]string{
+		"software": "WebCrawler",
+		"format":   "WARC File Format 1.1",
+	}))
+	info.Header.Set("WARC-Filename", name)
+	w.warcinfoID = info.ID()
+	return w.write(info)
+}
+
+// Close fecha o arquivo atual
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// warcFields serializa campos no formato application/warc-fields, em ordem alfabética
+func warcFields(fields map[string]string) []byte {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, fields[key])
+	}
+	return buf.Bytes()
+}
+
+// redactedHeaders cabeçalhos com credenciais ou sessão, gravados como Redacted
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Redacted valor gravado no lugar de cabeçalhos com credenciais ou sessão
+const Redacted = "[redacted]"
+
+// redactHeader retorna uma cópia do cabeçalho com credenciais e cookies ocultados
+func redactHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range redactedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			header[http.CanonicalHeaderKey(name)] = []string{Redacted}
+		}
+	}
+	return header
+}
+
+// requestBlock serializa a requisição HTTP enviada (linha de requisição e cabeçalhos)
+func requestBlock(req *http.Request) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+	fmt.Fprintf(&buf, "Host: %s\r\n", req.URL.Host)
+	_ = redactHeader(req.Header).Write(&buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// responseBlock serializa a resposta HTTP recebida (linha de status, cabeçalhos e corpo)
+func responseBlock(resp *http.Response, body []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/%d.%d %s\r\n", resp.ProtoMajor, resp.ProtoMinor, resp.Status)
+	_ = redactHeader(resp.Header).Write(&buf)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// NewExchange cria os registros request, response e metadata de uma busca.
+// Os registros request e metadata referenciam o response via WARC-Concurrent-To.
+func NewExchange(resp *http.Response, body []byte, truncated string, metadata map[string]string) []*Record {
+	target := resp.Request.URL.String()
+
+	response := NewRecord(TypeResponse, target, "application/http;msgtype=response", responseBlock(resp, body))
+	response.Header.Set("WARC-Payload-Digest", Digest(body))
+	if truncated != "" {
+		response.Header.Set("WARC-Truncated", truncated)
+	}
+
+	request := NewRecord(TypeRequest, target, "application/http;msgtype=request", requestBlock(resp.Request))
+	request.Header.Set("WARC-Concurrent-To", response.ID())
+
+	records := []*Record{response, request}
+	if len(metadata) > 0 {
+		meta := NewRecord(TypeMetadata, target, "application/warc-fields", warcFields(metadata))
+		meta.Header.Set("WARC-Concurrent-To", response.ID())
+		records = append(records, meta)
+	}
+	return records
+}